@@ -0,0 +1,105 @@
+package slasched
+
+import "fmt"
+
+type AdmissionPolicy int
+
+const (
+	ADMIT_ALL         AdmissionPolicy = iota
+	ADMIT_IF_FEASIBLE                 // reject procs that look like they will miss their SLA given the backlog
+	ADMIT_BY_PRICE                    // under overload, reject procs that pay less than ADMISSION_MIN_PRICE
+)
+
+// decides, when a proc gets to the LB, whether the LB takes it at all. Rejected procs are never
+// enqueued, so they are "never served" rather than "served late"
+type AdmissionController interface {
+	admit(ctx *LBCtx, p *Proc) bool
+}
+
+func newAdmissionController(ap AdmissionPolicy) AdmissionController {
+	switch ap {
+	case ADMIT_IF_FEASIBLE:
+		return &FeasibleAdmission{}
+	case ADMIT_BY_PRICE:
+		return &PriceAdmission{}
+	default:
+		return &AdmitAll{}
+	}
+}
+
+type AdmitAll struct{}
+
+func (aa *AdmitAll) admit(ctx *LBCtx, p *Proc) bool {
+	return true
+}
+
+// treats the whole LB as one queue where procs that pay more go first: the proc waits until the
+// backlog of procs paying at least as much is worked off, and then runs on one core
+type FeasibleAdmission struct{}
+
+func (fa *FeasibleAdmission) admit(ctx *LBCtx, p *Proc) bool {
+	finish := *ctx.currTickPtr + ctx.queueingDelay(p.willingToSpend(), p) + p.compGuess()
+	return finish <= p.deadlineGuess()
+}
+
+type PriceAdmission struct{}
+
+func (pa *PriceAdmission) admit(ctx *LBCtx, p *Proc) bool {
+	return ctx.queueingDelay(0, p) < ADMISSION_OVERLOAD || p.willingToSpend() >= ADMISSION_MIN_PRICE
+}
+
+// how long it would take the whole LB to work off the guessed work it has taken in and not
+// finished, of procs that pay at least minPrice, before it gets to p. p's own work is already in
+// the backlog (see procArrived) but p doesn't wait for itself, and a tick's worth of work is
+// already running rather than waiting, so neither counts
+func (ctx *LBCtx) queueingDelay(minPrice float32, p *Proc) Tftick {
+	if ctx.capacity == 0 {
+		return 0
+	}
+	backlog := Tftick(0)
+	for price, work := range ctx.backlog {
+		if price >= minPrice {
+			backlog += max(work, 0)
+		}
+	}
+	if p.willingToSpend() >= minPrice {
+		backlog = max(backlog-p.compGuess(), 0)
+	}
+	return max(backlog-Tftick(ctx.capacity), 0) / Tftick(ctx.capacity)
+}
+
+func (ctx *LBCtx) addBacklog(p *Proc, work Tftick) {
+	ctx.backlog[p.willingToSpend()] += work
+}
+
+// what the proc would pay if it ran, going off of the guess of its compute
+func (p *Proc) revenueGuess() float64 {
+	return float64(p.willingToSpend()) * float64(p.compGuess())
+}
+
+// called by the world for every proc that arrives, after procArrived; if it returns false, the LB
+// never sees the proc. In MARKET_MODE procs that bid less than the spot price are rejected too
+func (ctx *LBCtx) admit(p *Proc) bool {
+	if ctx.admission.admit(ctx, p) && !(MARKET_MODE && ctx.market.outbid(p)) {
+		return true
+	}
+
+	ctx.addBacklog(p, -p.compGuess())
+	ctx.market.leave(p)
+	ctx.stats.inc(priceStat("rejected", p.willingToSpend()), 1)
+	ctx.stats.inc(priceStat("rejected_revenue", p.willingToSpend()), p.revenueGuess())
+	return false
+}
+
+func (ctx *LBCtx) writeRejectionRates() {
+	for prio := 0; prio < N_PRIORITIES; prio++ {
+		price := mapPriorityToDollars(prio)
+		rejected := ctx.stats.get(priceStat("rejected", price))
+		if rejected == 0 {
+			continue
+		}
+		served := ctx.stats.get(priceStat("sla_met", price)) + ctx.stats.get(priceStat("sla_missed", price))
+		toWrite := fmt.Sprintf("%v, %v, %v, %.3f\n", ctx.nGenPerTick, ctx.lbName, priceStat("rejection_rate", price), rejected/(rejected+served))
+		logWrite(LB_STATS, toWrite)
+	}
+}
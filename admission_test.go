@@ -0,0 +1,28 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// on an otherwise empty LB a proc only has to wait for itself, so one that can make its SLA running
+// right away gets in
+func TestFeasibleAdmissionOwnWork(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 1)
+
+	pi := newPrivProc(3, 3, 1, 100)
+	pi.slaSlowdown = 1.5
+	p := newProvProc(0, currTick, pi)
+	ctx.procArrived(p)
+
+	if !(&FeasibleAdmission{}).admit(ctx, p) {
+		t.Fatalf("rejected a proc that would make its deadline on an empty LB")
+	}
+
+	// but with as much work again ahead of it, it would miss
+	other := newProvProc(1, currTick, newPrivProc(3, 3, 1, 100))
+	ctx.procArrived(other)
+	if (&FeasibleAdmission{}).admit(ctx, p) {
+		t.Fatalf("admitted a proc that has to wait for %v ticks of work first", other.compGuess())
+	}
+}
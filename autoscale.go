@@ -0,0 +1,95 @@
+package slasched
+
+type ScaleSignal int
+
+const (
+	SCALE_ON_UTIL    ScaleSignal = iota // fraction of core time used last tick
+	SCALE_ON_BACKLOG                    // procs waiting at the GSs, per machine
+	SCALE_ON_SLA                        // fraction of the procs that finished last tick that missed their SLA
+)
+
+// decides when to add and remove machines for the LBs that have individual machines; the LB itself
+// is responsible for actually booting them and for draining the ones that are to be removed
+type Autoscaler struct {
+	lastScaled Tftick
+	booting    []Tftick // when each of the machines that are booting will be ready
+	lastMet    float64
+	lastMissed float64
+}
+
+func newAutoscaler() *Autoscaler {
+	return &Autoscaler{
+		lastScaled: -SCALE_COOLDOWN,
+		booting:    make([]Tftick, 0),
+	}
+}
+
+func (as *Autoscaler) signal(ctx *LBCtx, util float64, backlog int, nMachines int) float64 {
+	switch SCALE_SIGNAL {
+	case SCALE_ON_BACKLOG:
+		return float64(backlog) / float64(nMachines)
+	case SCALE_ON_SLA:
+		met, missed := ctx.slaTotals()
+		newMet, newMissed := met-as.lastMet, missed-as.lastMissed
+		as.lastMet, as.lastMissed = met, missed
+		if newMet+newMissed == 0 {
+			return 0
+		}
+		return newMissed / (newMet + newMissed)
+	default:
+		return util
+	}
+}
+
+// returns how many booted machines are ready to join this tick, and how many machines should start
+// draining. nMachines is the ones taking procs, nDraining the ones on their way out
+func (as *Autoscaler) tick(ctx *LBCtx, util float64, backlog int, nMachines int, nDraining int) (int, int) {
+
+	now := *ctx.currTickPtr
+
+	// booting and draining machines cost as much as running ones
+	ctx.stats.inc("machine_ticks", float64(nMachines+nDraining+len(as.booting)))
+
+	nReady := 0
+	stillBooting := make([]Tftick, 0, len(as.booting))
+	for _, readyAt := range as.booting {
+		if readyAt <= now {
+			nReady += 1
+		} else {
+			stillBooting = append(stillBooting, readyAt)
+		}
+	}
+	as.booting = stillBooting
+
+	signal := as.signal(ctx, util, backlog, nMachines)
+
+	if now-as.lastScaled < SCALE_COOLDOWN {
+		return nReady, 0
+	}
+
+	if signal > SCALE_UP_THRESHOLD && nMachines+len(as.booting) < MAX_MACHINES {
+		for i := 0; i < SCALE_STEP; i++ {
+			as.booting = append(as.booting, now+BOOT_DELAY)
+		}
+		as.lastScaled = now
+		ctx.stats.inc("machines_added", SCALE_STEP)
+		return nReady, 0
+	}
+
+	if signal < SCALE_DOWN_THRESHOLD && len(as.booting) == 0 && nMachines-SCALE_STEP >= MIN_MACHINES {
+		as.lastScaled = now
+		ctx.stats.inc("machines_removed", SCALE_STEP)
+		return nReady, SCALE_STEP
+	}
+
+	return nReady, 0
+}
+
+func (ctx *LBCtx) slaTotals() (float64, float64) {
+	met, missed := 0.0, 0.0
+	for prio := 0; prio < N_PRIORITIES; prio++ {
+		met += ctx.stats.get(priceStat("sla_met", mapPriorityToDollars(prio)))
+		missed += ctx.stats.get(priceStat("sla_missed", mapPriorityToDollars(prio)))
+	}
+	return met, missed
+}
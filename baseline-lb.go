@@ -0,0 +1,181 @@
+package slasched
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// cheap placement policies to compare against, all with one dispatcher in front of hermod-style machines
+type BaselinePolicy int
+
+const (
+	PLACE_RANDOM BaselinePolicy = iota
+	PLACE_ROUND_ROBIN
+	PLACE_JSQ        // join the shortest queue, knowing every machine's queue
+	PLACE_POWER_OF_D // the shortest queue of BASELINE_D random machines
+)
+
+type BaselineLB struct {
+	currTickPtr   *Tftick
+	ctx           *LBCtx
+	policy        BaselinePolicy
+	machines      []*HermodMachine
+	failed        map[Tid]bool
+	procQ         []*Proc
+	roundRobinInd int
+	rand          *rand.Rand // its own, so that the other LBs see the same random numbers whether it runs or not
+}
+
+func newBaselineLB(fleet FleetSpec, nGenPerTick int, currTickPtr *Tftick, ctx *LBCtx, policy BaselinePolicy) *BaselineLB {
+
+	blb := &BaselineLB{
+		currTickPtr: currTickPtr,
+		ctx:         ctx,
+		policy:      policy,
+		machines:    make([]*HermodMachine, 0),
+		failed:      map[Tid]bool{},
+		procQ:       make([]*Proc, 0),
+		rand:        rand.New(rand.NewSource(SEED)),
+	}
+
+	ctx.enqProc = blb.enqProc
+
+	for i, mc := range fleet.machineClasses() {
+		blb.machines = append(blb.machines, newHermodMachine(Tid(i), mc.numCores, mc.mem, mc.speed, currTickPtr, nGenPerTick, ctx, BASELINE_LOCAL_POLICY))
+	}
+
+	return blb
+}
+
+func (blb *BaselineLB) getCtx() *LBCtx {
+	return blb.ctx
+}
+
+func (blb *BaselineLB) enqProc(p *Proc) {
+	blb.procQ = append(blb.procQ, p)
+}
+
+// procs that don't fit on the machine the policy picks wait for the next tick
+func (blb *BaselineLB) placeProcs() {
+
+	blb.ctx.net.deliver(*blb.currTickPtr)
+
+	procsToPlace := blb.procQ
+	blb.procQ = make([]*Proc, 0)
+
+	for _, p := range procsToPlace {
+		m, probeTime := blb.pickMachine(p)
+
+		toWrite := fmt.Sprintf("%v, baseline placing proc %v \n", int(*blb.currTickPtr), p.procId)
+		logWrite(blb.ctx.logs.sched, toWrite)
+
+		if m == nil || m.memFree() <= p.maxMem() {
+			blb.procQ = append(blb.procQ, p)
+			continue
+		}
+		blb.sendPlacement(m, p, probeTime)
+	}
+}
+
+func (blb *BaselineLB) sendPlacement(m *HermodMachine, p *Proc, after Tftick) {
+
+	place := func() {
+		if blb.failed[m.machineId] {
+			blb.procQ = append(blb.procQ, p)
+			return
+		}
+		m.placeProc(p)
+	}
+
+	blb.ctx.net.send(MSG_PLACE, after, place, func() { blb.procQ = append(blb.procQ, p) })
+}
+
+// whichever machine the policy says, see HermodGS.pickMachine for what is returned
+func (blb *BaselineLB) pickMachine(p *Proc) (*HermodMachine, Tftick) {
+
+	live := make([]*HermodMachine, 0, len(blb.machines))
+	for _, m := range blb.machines {
+		if !blb.failed[m.machineId] {
+			live = append(live, m)
+		}
+	}
+	if len(live) == 0 {
+		return nil, 0
+	}
+
+	switch blb.policy {
+	case PLACE_RANDOM:
+		return live[blb.rand.Intn(len(live))], 0
+
+	case PLACE_ROUND_ROBIN:
+		blb.roundRobinInd = (blb.roundRobinInd + 1) % len(live)
+		return live[blb.roundRobinInd], 0
+
+	case PLACE_JSQ:
+		// JSQ knows every machine's queue for free
+		all := make([]HermodProbe, len(live))
+		for i, m := range live {
+			all[i] = m.probeAnswer(p)
+		}
+		return shortestQueue(all, p), 0
+
+	default:
+		sampled := make([]HermodProbe, 0, BASELINE_D)
+		probeTime := Tftick(0)
+		for _, i := range blb.rand.Perm(len(live))[:min(BASELINE_D, len(live))] {
+			m := live[i]
+			ans, answered, rtt := probe(blb.ctx.net, func() HermodProbe { return m.probeAnswer(p) })
+			if !answered {
+				continue
+			}
+			probeTime = max(probeTime, rtt)
+			sampled = append(sampled, ans)
+		}
+		return shortestQueue(sampled, p), probeTime
+	}
+}
+
+// of the machines that p fits on, going off of what they answered
+func shortestQueue(answers []HermodProbe, p *Proc) *HermodMachine {
+	var shortest *HermodProbe
+	for i, ans := range answers {
+		if ans.memFree <= p.maxMem() {
+			continue
+		}
+		if shortest == nil || ans.load < shortest.load {
+			shortest = &answers[i]
+		}
+	}
+	if shortest == nil {
+		return nil
+	}
+	return shortest.m
+}
+
+func (blb *BaselineLB) tick() {
+
+	mids := make([]Tid, len(blb.machines))
+	for i, m := range blb.machines {
+		mids[i] = m.machineId
+	}
+
+	failed, recovered := blb.ctx.failures.tick(*blb.currTickPtr, mids)
+	for _, mid := range recovered {
+		delete(blb.failed, mid)
+		blb.ctx.machineRecovered(mid)
+	}
+	for _, mid := range failed {
+		blb.ctx.machineFailed()
+		blb.failed[mid] = true
+		for _, p := range blb.machines[mid].fail() {
+			blb.ctx.procLost(p)
+			blb.procQ = append(blb.procQ, p)
+		}
+	}
+
+	for _, m := range blb.machines {
+		if !blb.failed[m.machineId] {
+			m.tick()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// JSQ and power of d send a proc to the shortest queue it fits on; a proc that fits nowhere waits at
+// the dispatcher
+func TestBaselineShortestQueue(t *testing.T) {
+	for _, policy := range []BaselinePolicy{PLACE_JSQ, PLACE_POWER_OF_D} {
+		currTick := Tftick(0)
+		ctx := newLBCtx("test", 0, &currTick, 8)
+		blb := newBaselineLB(uniformFleet(2, 4), 0, &currTick, ctx, policy)
+
+		blb.machines[0].placeProc(newTestProc(0, 10, 1, 100))
+		blb.machines[0].placeProc(newTestProc(1, 10, 1, 100))
+
+		p := newTestProc(2, 10, 1, 100)
+		tooBig := newTestProc(3, 10, 1, MEM_PER_MACHINE)
+		blb.enqProc(p)
+		blb.enqProc(tooBig)
+		blb.placeProcs()
+
+		if len(blb.machines[1].procQ) != 1 || blb.machines[1].procQ[0] != p {
+			t.Fatalf("policy %v didn't place the proc on the emptier machine", policy)
+		}
+		if len(blb.procQ) != 1 || blb.procQ[0] != tooBig {
+			t.Fatalf("policy %v left %v at the dispatcher, want just the proc that fits nowhere", policy, blb.procQ)
+		}
+	}
+}
+
+// round robin deals procs out in turn, no matter how loaded the machines are
+func TestBaselineRoundRobin(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 8)
+	blb := newBaselineLB(uniformFleet(2, 4), 0, &currTick, ctx, PLACE_ROUND_ROBIN)
+
+	for i := 0; i < 4; i++ {
+		blb.enqProc(newTestProc(Tid(i), 10, 1, 100))
+	}
+	blb.placeProcs()
+
+	if len(blb.machines[0].procQ) != 2 || len(blb.machines[1].procQ) != 2 {
+		t.Fatalf("placed %v and %v procs, want 2 on each", len(blb.machines[0].procQ), len(blb.machines[1].procQ))
+	}
+}
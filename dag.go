@@ -0,0 +1,183 @@
+package slasched
+
+import (
+	"fmt"
+)
+
+// a request made up of several procs: children get released once all of their parents are done, and
+// the request as a whole is done once all of its procs are
+type DAG struct {
+	nodes       []*ProcInternals // nodes[0] is the root
+	timeStarted Tftick
+}
+
+func (d *DAG) actualCritPath() Tftick {
+	return d.nodes[0].actualCritPath
+}
+
+// turns root into the root of a DAG, either a chain or a fan-out to a number of workers that then
+// fan back in to a single aggregator
+func genDAG(root *ProcInternals, genNode func() *ProcInternals) *DAG {
+
+	dag := &DAG{nodes: []*ProcInternals{root}}
+	root.dag = dag
+
+	addChild := func(parent *ProcInternals, child *ProcInternals) {
+		parent.children = append(parent.children, child)
+		child.nParents += 1
+		if child.dag == nil {
+			child.dag = dag
+			dag.nodes = append(dag.nodes, child)
+		}
+	}
+
+	if r.Intn(2) == 0 {
+		chainLen := 2 + r.Intn(DAG_MAX_CHAIN-1)
+		prev := root
+		for i := 1; i < chainLen; i++ {
+			next := genNode()
+			addChild(prev, next)
+			prev = next
+		}
+	} else {
+		fanOut := 2 + r.Intn(DAG_MAX_FANOUT-1)
+		join := genNode()
+		for i := 0; i < fanOut; i++ {
+			worker := genNode()
+			addChild(root, worker)
+			addChild(worker, join)
+		}
+	}
+
+	setCritPath(root)
+
+	return dag
+}
+
+// works out the real critical path from every node of root's DAG on, which is what the SLA gets
+// checked against; the LBs only get to guess at it, see DAGTracker.guessCritPaths
+func setCritPath(root *ProcInternals) {
+	paths := make(map[*ProcInternals]Tftick)
+	critPathFrom(root, func(pi *ProcInternals) Tftick { return pi.actualComp }, paths)
+	for pi, cp := range paths {
+		pi.actualCritPath = cp
+	}
+}
+
+// the longest path from pi to the end of the DAG, pi itself included, if every node takes comp;
+// paths has the ones already worked out, and gets the new ones
+func critPathFrom(pi *ProcInternals, comp func(*ProcInternals) Tftick, paths map[*ProcInternals]Tftick) Tftick {
+	if cp, ok := paths[pi]; ok {
+		return cp
+	}
+
+	longestAfter := Tftick(0)
+	for _, child := range pi.children {
+		longestAfter = max(longestAfter, critPathFrom(child, comp, paths))
+	}
+
+	paths[pi] = comp(pi) + longestAfter
+	return paths[pi]
+}
+
+// per-LB bookkeeping of how far along each DAG is; each LB runs its own copy of every DAG
+type DAGTracker struct {
+	parentsDone map[*ProcInternals]int
+	nodesLeft   map[*DAG]int
+	dropped     map[*DAG]bool             // DAGs that lost a node, so they can't finish anymore
+	critPath    map[*ProcInternals]Tftick // the LB's guess of the longest path from the node on
+}
+
+func newDAGTracker() *DAGTracker {
+	return &DAGTracker{
+		parentsDone: make(map[*ProcInternals]int),
+		nodesLeft:   make(map[*DAG]int),
+		dropped:     make(map[*DAG]bool),
+		critPath:    make(map[*ProcInternals]Tftick),
+	}
+}
+
+// the first time the LB sees a node of the DAG, it guesses the critical path from every node on
+// with its predictor, same as it guesses the compute of procs on their own
+func (dt *DAGTracker) guessCritPaths(ctx *LBCtx, dag *DAG) {
+	if _, ok := dt.critPath[dag.nodes[0]]; ok {
+		return
+	}
+	guess := func(pi *ProcInternals) Tftick {
+		p := newProvProc(pi.reservedId, 0, pi)
+		p.instanceMem = instanceMem()
+		return ctx.predictor.predict(p)
+	}
+	critPathFrom(dag.nodes[0], guess, dt.critPath)
+}
+
+// called when a node of a DAG arrives at the LB, for the guessed deadline to go off of
+func (dt *DAGTracker) nodeArrived(ctx *LBCtx, p *Proc) {
+	dag := p.procInternals.dag
+	dt.guessCritPaths(ctx, dag)
+
+	p.dagCritPath = dt.critPath[dag.nodes[0]]
+	for _, child := range p.procInternals.children {
+		p.critPathLeft = max(p.critPathLeft, dt.critPath[child])
+	}
+}
+
+func (dt *DAGTracker) forget(dag *DAG) {
+	delete(dt.nodesLeft, dag)
+	for _, node := range dag.nodes {
+		delete(dt.parentsDone, node)
+		delete(dt.critPath, node)
+	}
+}
+
+// records that p finished and releases any of its children that were only waiting on p
+func (dt *DAGTracker) nodeDone(ctx *LBCtx, p *Proc) {
+
+	dag := p.procInternals.dag
+
+	// whatever else of a dropped DAG was still running finishes, but nothing after it is released
+	if dt.dropped[dag] {
+		return
+	}
+
+	if _, ok := dt.nodesLeft[dag]; !ok {
+		dt.nodesLeft[dag] = len(dag.nodes)
+	}
+	dt.nodesLeft[dag] -= 1
+
+	for _, child := range p.procInternals.children {
+		dt.parentsDone[child] += 1
+		if dt.parentsDone[child] < child.nParents {
+			continue
+		}
+		delete(dt.parentsDone, child)
+
+		childProc := newProvProc(child.reservedId, p.timeDone, child)
+		ctx.procArrived(childProc)
+		ctx.enqProc(childProc)
+	}
+
+	if dt.nodesLeft[dag] == 0 {
+		toWrite := fmt.Sprintf("%v, %v, %v, %v, %v, %v \n", ctx.nGenPerTick, ctx.lbName, p.willingToSpend(), len(dag.nodes), (p.timeDone - dag.timeStarted).String(), dt.critPath[dag.nodes[0]].String())
+		logWrite(DAGS_DONE, toWrite)
+
+		dt.forget(dag)
+
+		ctx.stats.inc("dags_done", 1)
+		ctx.stats.inc("dag_latency", float64(p.timeDone-dag.timeStarted))
+	}
+}
+
+// records that p was dropped without finishing (eg EXPIRED_DROP in edf-lb.go): the DAG as a whole
+// fails, and none of the rest of it gets released
+func (dt *DAGTracker) nodeDropped(ctx *LBCtx, p *Proc) {
+
+	dag := p.procInternals.dag
+	if dt.dropped[dag] {
+		return
+	}
+	dt.dropped[dag] = true
+	dt.forget(dag)
+
+	ctx.stats.inc("dags_dropped", 1)
+}
@@ -0,0 +1,40 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// hands out the same procs once, then nothing
+type fixedLoadGen struct {
+	procs []*ProcInternals
+}
+
+func (lg *fixedLoadGen) genLoad(nProcs int) []*ProcInternals {
+	procs := lg.procs
+	lg.procs = nil
+	return procs
+}
+
+// a DAG whose root arrives at some tick gets deadlines relative to that tick, already when the LBs
+// first see the root
+func TestDAGRootDeadlineFromArrival(t *testing.T) {
+	w := newWorld(1, 1, 0, 1, []LBType{EDF_DROP})
+	w.currTick = 50
+
+	root, child := newPrivProc(1, 1, 1, 100), newPrivProc(1, 1, 1, 100)
+	root.slaSlowdown, child.slaSlowdown = 3, 3
+	newTestDAG(0, []*ProcInternals{root, child}, [][2]int{{0, 1}})
+	w.loadGen = &fixedLoadGen{procs: []*ProcInternals{root}}
+
+	w.genLoad(1)
+
+	elb := w.LBs[0].(*EDFLB)
+	if len(elb.procs) != 1 || elb.procs[0].dl != 50+3 {
+		t.Fatalf("root of a DAG arriving at 50 queued with %v, want a deadline of %v", elb.procs, 50+3)
+	}
+
+	w.Tick(0)
+	if dropped := elb.ctx.stats.get("dags_dropped"); dropped != 0 {
+		t.Fatalf("%v DAGs dropped right after they arrived", dropped)
+	}
+}
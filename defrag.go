@@ -0,0 +1,174 @@
+package slasched
+
+import (
+	"fmt"
+	"sort"
+)
+
+// a migrated proc keeps its progress, but is paused while its memory is copied over. Other than that
+// the machine places it like any other proc, it just doesn't count as placed again; returns how long
+// it is paused, and what the machine killed to make room (nothing, if the plan was right)
+func (sd *Machine) migrateIn(p *Proc) (Tftick, []*Proc) {
+	cost := Tftick(MIGRATION_COST_PER_MEM * float64(p.memUsed()))
+	p.blockedUntil = max(p.blockedUntil, *sd.currTickPtr+cost)
+	p.migrating = true
+	_, _, killed := sd.placeProc(p, p.gsId)
+	return cost, killed
+}
+
+// if the biggest proc waiting at any of the GSs doesn't fit on any machine, even though there is
+// enough memory free overall, move small procs off one of the machines with the most free memory
+// until it does fit, and send the big proc there. Of the DEFRAG_CANDIDATES machines with the most
+// free memory, the one that needs the least memory moved wins
+func (mlb *MineLB) defrag() {
+
+	var bigProc *Proc
+	var bigGs *MineGSS
+	for _, gs := range mlb.GSSs {
+		for _, q := range gs.multiq.qMap {
+			for _, p := range q.getQ() {
+				if bigProc == nil || p.maxMem() > bigProc.maxMem() {
+					bigProc = p
+					bigGs = gs
+				}
+			}
+		}
+	}
+	if bigProc == nil {
+		return
+	}
+
+	live := make([]*Machine, 0, len(mlb.machines))
+	totalFree := Tmem(0)
+	for _, m := range mlb.machines {
+		if m.failed || m.draining {
+			continue
+		}
+		live = append(live, m)
+		totalFree += m.memFree()
+	}
+	if len(live) == 0 || totalFree <= 0 {
+		return
+	}
+	sort.Slice(live, func(i, j int) bool {
+		if live[i].memFree() == live[j].memFree() {
+			return live[i].machineId < live[j].machineId
+		}
+		return live[i].memFree() > live[j].memFree()
+	})
+	maxFree := live[0].memFree()
+
+	// how much of the free memory is not in the biggest hole
+	fragmentation := 1 - float64(maxFree)/float64(totalFree)
+	if bigProc.maxMem() < maxFree || bigProc.maxMem() >= totalFree || fragmentation < DEFRAG_THRESHOLD {
+		return
+	}
+
+	mlb.ctx.stats.inc("defrag_attempts", 1)
+
+	var target *Machine
+	var moves map[*Proc]*Machine
+	movedMem := Tmem(0)
+	for _, m := range live[:min(DEFRAG_CANDIDATES, len(live))] {
+		if mMoves, mMovedMem, ok := planDefrag(m, bigProc, live); ok && (target == nil || mMovedMem < movedMem) {
+			target, moves, movedMem = m, mMoves, mMovedMem
+		}
+	}
+
+	if target == nil {
+		mlb.ctx.stats.inc("defrag_gave_up", 1)
+		return
+	}
+
+	onTarget := make([]*Proc, len(target.activeQ.getQ()))
+	copy(onTarget, target.activeQ.getQ())
+	for _, p := range onTarget {
+		dst, ok := moves[p]
+		if !ok {
+			continue
+		}
+		target.activeQ.kill(p.procId)
+		cost, killed := dst.migrateIn(p)
+		mlb.ctx.net.count(MSG_MIGRATE, 1)
+		for _, k := range killed {
+			mlb.GSSs[k.gsId].toReq = append(mlb.GSSs[k.gsId].toReq, k)
+		}
+
+		mlb.ctx.stats.inc("defrag_migrations", 1)
+		mlb.ctx.stats.inc("defrag_migrated_mem", float64(p.memUsed()))
+		mlb.ctx.stats.inc("defrag_cost", float64(cost))
+	}
+
+	mlb.ctx.stats.inc("defrag_placed", 1)
+	bigGs.multiq.qMap[bigProc.willingToSpend()].kill(bigProc.procId)
+	bigGs.sendPlacement(target, bigProc, 0)
+}
+
+// plans the moves (smallest procs first) that would make room for bigProc on target, without
+// migrating anything yet; returns where each proc would go and how much memory that moves, and
+// whether it makes enough room at all
+func planDefrag(target *Machine, bigProc *Proc, live []*Machine) (map[*Proc]*Machine, Tmem, bool) {
+
+	toMove := make([]*Proc, len(target.activeQ.getQ()))
+	copy(toMove, target.activeQ.getQ())
+	sort.Slice(toMove, func(i, j int) bool { return toMove[i].memCharged() < toMove[j].memCharged() })
+
+	memFree := make(map[*Machine]Tmem, len(live))
+	for _, m := range live {
+		memFree[m] = m.memFree()
+	}
+	moves := make(map[*Proc]*Machine)
+	movedMem := Tmem(0)
+	for _, p := range toMove {
+		if bigProc.maxMem() < memFree[target] || len(moves) >= DEFRAG_MAX_MIGRATIONS {
+			break
+		}
+		// moving a swapped out proc doesn't free anything
+		if p.memCharged() == 0 {
+			continue
+		}
+
+		// best fit, so we don't just make a new hole that is too small somewhere else
+		var dst *Machine
+		for _, m := range live {
+			if m == target || p.memCharged() >= memFree[m] {
+				continue
+			}
+			if dst == nil || memFree[m] < memFree[dst] {
+				dst = m
+			}
+		}
+		if dst == nil {
+			continue
+		}
+
+		moves[p] = dst
+		movedMem += p.memCharged()
+		memFree[dst] -= p.memCharged()
+		memFree[target] += p.memCharged()
+	}
+
+	return moves, movedMem, bigProc.maxMem() < memFree[target]
+}
+
+// how much it costs to make room for a big proc by moving procs out of the way, against making room
+// for one by killing procs: the time the migrated procs are paused per proc defrag placed, over the
+// work lost (or time spent saving and swapping) per placement that killed. Only defined if both
+// happened
+func (ctx *LBCtx) defragVsKill() (float64, bool) {
+	nDefrags, nKills := ctx.stats.get("defrag_placed"), ctx.stats.get("placement_kills")
+	killCost := ctx.stats.get("evict_wasted_work") + ctx.stats.get("evict_checkpoint_time") + ctx.stats.get("evict_swap_time")
+	if nDefrags == 0 || nKills == 0 || killCost == 0 {
+		return 0, false
+	}
+	return (ctx.stats.get("defrag_cost") / nDefrags) / (killCost / nKills), true
+}
+
+func (ctx *LBCtx) writeDefragVsKill() {
+	ratio, ok := ctx.defragVsKill()
+	if !ok {
+		return
+	}
+	toWrite := fmt.Sprintf("%v, %v, %v, %.3f\n", ctx.nGenPerTick, ctx.lbName, "defrag_vs_kill_cost", ratio)
+	logWrite(LB_STATS, toWrite)
+}
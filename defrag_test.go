@@ -0,0 +1,69 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// moving a swapped out proc frees nothing on the target, so the plan leaves it where it is
+func TestPlanDefragSkipsSwapped(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 8)
+	mlb := newMineLB(uniformFleet(2, 4), 0, 1, &currTick, ctx)
+	target, other := mlb.machines[0], mlb.machines[1]
+
+	swapped := newTestProc(0, 10, 1, 1000)
+	swapped.swapped = true
+	small := newTestProc(1, 10, 1, 1000)
+	target.placeProc(swapped, 0)
+	target.placeProc(small, 0)
+
+	bigProc := newTestProc(2, 10, 1, int(target.memFree())+500)
+	moves, movedMem, ok := planDefrag(target, bigProc, []*Machine{target, other})
+	if !ok || len(moves) != 1 || moves[small] != other || movedMem != small.memCharged() {
+		t.Fatalf("planned %v moving %v (ok: %v), want just the small proc moved to the other machine", moves, movedMem, ok)
+	}
+}
+
+// a migrated proc goes through the machine's usual placement, but is paused while it moves and
+// doesn't count as placed a second time
+func TestMigrateInPlaces(t *testing.T) {
+	currTick := Tftick(5)
+	ctx := newLBCtx("test", 0, &currTick, 8)
+	mlb := newMineLB(uniformFleet(2, 4), 0, 1, &currTick, ctx)
+	src, dst := mlb.machines[0], mlb.machines[1]
+
+	p := newTestProc(0, 10, 1, 1000)
+	src.placeProc(p, 0)
+	src.activeQ.kill(p.procId)
+
+	memFree := dst.memFree()
+	cost, killed := dst.migrateIn(p)
+	if len(killed) != 0 || dst.activeQ.qlen() != 1 || dst.memFree() != memFree-p.memCharged() {
+		t.Fatalf("migrated proc wasn't placed on the destination like any other proc")
+	}
+	if p.blockedUntil != currTick+cost || p.migrating {
+		t.Fatalf("migrated proc blocked until %v (migrating: %v), want %v", p.blockedUntil, p.migrating, currTick+cost)
+	}
+	if placed := ctx.stats.get("procs_placed"); placed != 1 {
+		t.Fatalf("%v procs placed, want the migration not to count", placed)
+	}
+}
+
+// defrag that pauses procs for 1 tick per big proc it places is a fifth as costly as kills that
+// lose 5 ticks of work each
+func TestDefragVsKill(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 8)
+
+	if _, ok := ctx.defragVsKill(); ok {
+		t.Fatalf("compared defrag and kills before either happened")
+	}
+
+	ctx.stats.inc("defrag_placed", 2)
+	ctx.stats.inc("defrag_cost", 2)
+	ctx.stats.inc("placement_kills", 4)
+	ctx.stats.inc("evict_wasted_work", 20)
+	if ratio, ok := ctx.defragVsKill(); !ok || ratio < 0.199 || ratio > 0.201 {
+		t.Fatalf("defrag vs kill cost %v (ok: %v), want 0.2", ratio, ok)
+	}
+}
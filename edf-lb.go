@@ -1,39 +1,128 @@
 package slasched
 
 import (
-	"math"
 	"strconv"
 )
 
 type EDFProc struct {
-	p  *Proc
-	dl float32
+	p       *Proc
+	dl      float32
+	demoted bool // its deadline passed and it is best effort now, see EXPIRED_DEMOTE
 }
 
 func (edfp *EDFProc) String() string {
 	return edfp.p.String() + ", dl: " + strconv.FormatFloat(float64(edfp.dl), 'f', 3, 32)
 }
 
+type EDFOrder int
+
+const (
+	ORDER_EDF EDFOrder = iota
+	ORDER_LLF          // least laxity first, ie deadline minus the work the proc (probably) has left
+)
+
+// what to do with procs whose deadline has already passed
+type ExpiredPolicy int
+
+const (
+	EXPIRED_KEEP   ExpiredPolicy = iota
+	EXPIRED_DROP                 // abort them; they count as missing their SLA
+	EXPIRED_DEMOTE               // only run them when nothing that can still make its deadline is waiting
+)
+
+type EDFVariant struct {
+	order   EDFOrder
+	expired ExpiredPolicy
+}
+
+func (v EDFVariant) key(p *EDFProc) float32 {
+	if v.order == ORDER_LLF {
+		return p.dl - float32(p.p.remainingGuess())
+	}
+	return p.dl
+}
+
+// takes the most urgent proc out of q
+func (v EDFVariant) deq(q []*EDFProc) (*EDFProc, []*EDFProc) {
+	var procToRet *EDFProc
+	idxToDel := -1
+
+	for i, p := range q {
+		if procToRet == nil || (!p.demoted && procToRet.demoted) || (p.demoted == procToRet.demoted && v.key(p) < v.key(procToRet)) {
+			procToRet = p
+			idxToDel = i
+		}
+	}
+
+	if idxToDel >= 0 {
+		q = append(q[:idxToDel], q[idxToDel+1:]...)
+	}
+
+	return procToRet, q
+}
+
+// drops or demotes the procs in q whose deadline is before now; returns the ones left in q, and the
+// ones that were dropped
+func (v EDFVariant) expire(ctx *LBCtx, q []*EDFProc, now Tftick) ([]*EDFProc, []*EDFProc) {
+	if v.expired == EXPIRED_KEEP {
+		return q, nil
+	}
+
+	kept := make([]*EDFProc, 0, len(q))
+	dropped := make([]*EDFProc, 0)
+	for _, p := range q {
+		if p.demoted || Tftick(p.dl) >= now {
+			kept = append(kept, p)
+			continue
+		}
+		if v.expired == EXPIRED_DEMOTE {
+			p.demoted = true
+			ctx.stats.inc(priceStat("deadline_demoted", p.p.willingToSpend()), 1)
+			kept = append(kept, p)
+			continue
+		}
+		ctx.stats.inc(priceStat("deadline_dropped", p.p.willingToSpend()), 1)
+		ctx.stats.inc(priceStat("sla_missed", p.p.willingToSpend()), 1)
+		ctx.stats.inc("deadline_dropped_work", float64(p.p.compDone))
+		ctx.addBacklog(p.p, -p.p.compGuess())
+		ctx.market.leave(p.p)
+		if p.p.procInternals.dag != nil {
+			ctx.dags.nodeDropped(ctx, p.p)
+		}
+		dropped = append(dropped, p)
+	}
+	return kept, dropped
+}
+
 type EDFLB struct {
-	procs      []*EDFProc
-	bigMachine *BigEDFMachine
+	ctx         *LBCtx
+	currTickPtr *Tftick
+	procs       []*EDFProc
+	bigMachine  *BigEDFMachine
+	variant     EDFVariant
 }
 
-func newEDFLB(numMachines int, numCores int, nGenPerTick int, currrTickPtr *Tftick) *EDFLB {
+func newEDFLB(fleet FleetSpec, nGenPerTick int, currrTickPtr *Tftick, ctx *LBCtx, variant EDFVariant) *EDFLB {
 	ilb := &EDFLB{
-		procs:      make([]*EDFProc, 0),
-		bigMachine: newBigEDFMachine(numMachines*numCores, Tmem(numMachines*MEM_PER_MACHINE), currrTickPtr, nGenPerTick),
+		ctx:         ctx,
+		currTickPtr: currrTickPtr,
+		procs:       make([]*EDFProc, 0),
+		bigMachine:  newBigEDFMachine(fleet.coreSpeeds(), fleet.totalMem(), currrTickPtr, nGenPerTick, ctx, variant),
+		variant:     variant,
 	}
 
+	ctx.enqProc = ilb.enqProc
+
 	return ilb
 }
 
-func (elb *EDFLB) enqProc(proc *Proc) {
+func (elb *EDFLB) getCtx() *LBCtx {
+	return elb.ctx
+}
 
-	topPrice := mapPriorityToDollars(N_PRIORITIES - 1)
+func (elb *EDFLB) enqProc(proc *Proc) {
 
-	newDl := float32(proc.timeStarted) + float32(proc.procInternals.compGuess)*(topPrice/proc.procInternals.willingToSpend)
-	edfP := &EDFProc{p: proc, dl: newDl}
+	edfP := &EDFProc{p: proc, dl: float32(proc.deadlineGuess())}
 
 	elb.enq(edfP)
 
@@ -41,12 +130,16 @@ func (elb *EDFLB) enqProc(proc *Proc) {
 
 func (elb *EDFLB) placeProcs() {
 
+	elb.procs, _ = elb.variant.expire(elb.ctx, elb.procs, *elb.currTickPtr)
+
 	toReq := make([]*EDFProc, 0)
 
 	p := elb.deq()
 
 	for p != nil {
-		placed := elb.bigMachine.potPlaceProc(p)
+		placed, killed := elb.bigMachine.potPlaceProc(p)
+
+		toReq = append(toReq, killed...)
 
 		if !placed {
 			toReq = append(toReq, p)
@@ -65,22 +158,8 @@ func (elb *EDFLB) tick() {
 }
 
 func (elb *EDFLB) deq() *EDFProc {
-	minDl := float32(math.MaxFloat32)
 	var procToRet *EDFProc
-	idxToDel := -1
-
-	for i, p := range elb.procs {
-		if p.dl < minDl {
-			minDl = p.dl
-			procToRet = p
-			idxToDel = i
-		}
-	}
-
-	if idxToDel >= 0 {
-		elb.procs = append(elb.procs[:idxToDel], elb.procs[idxToDel+1:]...)
-	}
-
+	procToRet, elb.procs = elb.variant.deq(elb.procs)
 	return procToRet
 }
 
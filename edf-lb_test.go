@@ -0,0 +1,66 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// a proc still waiting at the LB after its deadline passed gets dropped, and counts as missing its SLA
+func TestEDFDropExpired(t *testing.T) {
+	currTick := Tftick(5)
+	fleet := uniformFleet(1, 1)
+	ctx := newLBCtx("test", 0, &currTick, fleet.totalCompute())
+	elb := newEDFLB(fleet, 0, &currTick, ctx, EDFVariant{ORDER_EDF, EXPIRED_DROP})
+
+	pi := newPrivProc(1, 1, 1, 100)
+	pi.slaDeadline = 1
+	p := newProvProc(0, 1, pi)
+	ctx.procArrived(p)
+	elb.enqProc(p)
+	elb.placeProcs()
+
+	if len(elb.procs) != 0 || len(elb.bigMachine.procQ) != 0 {
+		t.Fatalf("expired proc wasn't dropped")
+	}
+	if missed := ctx.stats.get(priceStat("sla_missed", 1)); missed != 1 {
+		t.Fatalf("%v SLAs missed, want 1", missed)
+	}
+	if dropped := ctx.stats.get(priceStat("deadline_dropped", 1)); dropped != 1 {
+		t.Fatalf("%v procs dropped, want 1", dropped)
+	}
+}
+
+// dropping a node of a DAG fails the DAG: the rest of it is never released
+func TestEDFDropSettlesDAG(t *testing.T) {
+	currTick := Tftick(5)
+	fleet := uniformFleet(1, 1)
+	ctx := newLBCtx("test", 0, &currTick, fleet.totalCompute())
+	elb := newEDFLB(fleet, 0, &currTick, ctx, EDFVariant{ORDER_EDF, EXPIRED_DROP})
+
+	root := newPrivProc(1, 1, 1, 100)
+	left, right := newPrivProc(1, 1, 1, 100), newPrivProc(1, 1, 1, 100)
+	join := newPrivProc(1, 1, 1, 100)
+	dag := newTestDAG(0, []*ProcInternals{root, left, right, join}, [][2]int{{0, 1}, {0, 2}, {1, 3}, {2, 3}})
+	for _, pi := range dag.nodes {
+		pi.slaDeadline = 1
+	}
+
+	// the left worker already ran, the right one is stuck at the LB past its deadline
+	leftProc := newProvProc(1, 0, left)
+	leftProc.timeDone = 2
+	ctx.procDone(leftProc)
+
+	rightProc := newProvProc(2, 0, right)
+	ctx.procArrived(rightProc)
+	elb.enqProc(rightProc)
+	elb.placeProcs()
+
+	if dropped := ctx.stats.get("dags_dropped"); dropped != 1 {
+		t.Fatalf("%v DAGs dropped, want 1", dropped)
+	}
+	if len(elb.procs) != 0 {
+		t.Fatalf("the join of a dropped DAG got released")
+	}
+	if len(ctx.dags.nodesLeft) != 0 || len(ctx.dags.parentsDone) != 0 {
+		t.Fatalf("tracker still waiting on the dropped DAG")
+	}
+}
@@ -9,42 +9,71 @@ type BigEDFMachine struct {
 	currTickPtr             *Tftick
 	procQ                   []*EDFProc
 	amtWorkPerTick          int
+	coreSpeeds              []float64
 	totalMem                Tmem
 	worldNumProcsGenPerTick int
+	ctx                     *LBCtx
+	coreHist                CoreHistory
+	warm                    *WarmCache
+	power                   *PowerState
+	variant                 EDFVariant
 }
 
-func newBigEDFMachine(amtWorkPerTick int, totMem Tmem, currTickPtr *Tftick, worldNumProcsGenPerTick int) *BigEDFMachine {
+func newBigEDFMachine(coreSpeeds []float64, totMem Tmem, currTickPtr *Tftick, worldNumProcsGenPerTick int, ctx *LBCtx, variant EDFVariant) *BigEDFMachine {
 	return &BigEDFMachine{
 		currTickPtr:             currTickPtr,
 		procQ:                   make([]*EDFProc, 0),
-		amtWorkPerTick:          amtWorkPerTick,
+		amtWorkPerTick:          len(coreSpeeds),
+		coreSpeeds:              coreSpeeds,
 		totalMem:                totMem,
 		worldNumProcsGenPerTick: worldNumProcsGenPerTick,
+		ctx:                     ctx,
+		coreHist:                CoreHistory{},
+		warm:                    newWarmCache(totMem),
+		power:                   newPowerState(),
+		variant:                 variant,
 	}
 
 }
 
-func (edfm *BigEDFMachine) potPlaceProc(newProc *EDFProc) bool {
+func (edfm *BigEDFMachine) potPlaceProc(newProc *EDFProc) (bool, []*EDFProc) {
 
-	// if it just fits in terms of memory do it
-	if newProc.p.maxMem() < edfm.memFree() {
+	// if it just fits in terms of memory do it; the warm instances can all make way for it, but only
+	// get dropped once it is actually placed
+	memFree := edfm.memFree() + edfm.warm.mem()
+	if newProc.p.maxMem() < memFree {
 
 		newProc.p.timePlaced = *edfm.currTickPtr
+		edfm.warm.makeRoom(newProc.p, edfm.memFree())
+		edfm.ctx.startInstance(newProc.p, edfm.warm)
+		edfm.ctx.restore(newProc.p)
+		edfm.ctx.wake(edfm.power, newProc.p)
 		edfm.enq(newProc)
-		return true
+		return true, nil
 	}
 
-	// if it doesn't fit, look if there a good proc to kill? (/a combination of procs? can add that later)
-	procToKill, timeToProfit := edfm.checkKill(newProc)
-	if timeToProfit < TIME_TO_PROFIT_THRESHOLD {
+	// if it doesn't fit, look if there are good procs to kill
+	victims, lost := pickVictims(edfm.procs(), newProc.p, newProc.p.maxMem()-memFree+1)
+	if timeToProfit(newProc.p, victims, lost) < TIME_TO_PROFIT_THRESHOLD {
+
+		killed := make([]*EDFProc, 0, len(victims))
+		for _, p := range victims {
+			if edfm.ctx.evict(p) {
+				killed = append(killed, edfm.kill(p.procId))
+			}
+		}
+		edfm.ctx.evicted(victims)
 
 		newProc.p.timePlaced = *edfm.currTickPtr
-		edfm.kill(procToKill)
+		edfm.warm.makeRoom(newProc.p, edfm.memFree())
+		edfm.ctx.startInstance(newProc.p, edfm.warm)
+		edfm.ctx.restore(newProc.p)
+		edfm.ctx.wake(edfm.power, newProc.p)
 		edfm.enq(newProc)
-		return true
+		return true, killed
 	}
 
-	return false
+	return false, nil
 
 }
 
@@ -52,21 +81,56 @@ func (edfm *BigEDFMachine) memFree() Tmem {
 	currMemUsed := Tmem(0)
 
 	for _, p := range edfm.procQ {
-		currMemUsed += p.p.maxMem()
+		currMemUsed += p.p.memCharged()
+	}
+
+	return edfm.totalMem - currMemUsed - edfm.warm.mem()
+}
+
+func (edfm *BigEDFMachine) procs() []*Proc {
+	procs := make([]*Proc, 0, len(edfm.procQ))
+	for _, p := range edfm.procQ {
+		procs = append(procs, p.p)
 	}
+	return procs
+}
+
+func (edfm *BigEDFMachine) checkOOM() {
+	procs := edfm.procs()
 
-	return edfm.totalMem - currMemUsed
+	for physMemUsed(procs)+edfm.warm.mem() > edfm.totalMem {
+		if edfm.warm.drop() {
+			continue
+		}
+		victim := pickOOMVictim(procs)
+		edfm.kill(victim.procId)
+		for i, p := range procs {
+			if p == victim {
+				procs = append(procs[:i], procs[i+1:]...)
+				break
+			}
+		}
+		edfm.ctx.oomKilled(victim)
+	}
 }
 
 func (edfm *BigEDFMachine) tick() {
 
-	toWrite := fmt.Sprintf("%v @ %v; mem free: %v: WHOLE QUEUE ", edfm.worldNumProcsGenPerTick, edfm.currTickPtr, MEM_PER_MACHINE)
-	logWrite(EDF_SCHED, toWrite)
+	edfm.ctx.swapIn(edfm.procs(), edfm.memFree())
+
+	toWrite := fmt.Sprintf("%v%v @ %v; mem free: %v: WHOLE QUEUE ", edfm.ctx.logs.prefix, edfm.worldNumProcsGenPerTick, edfm.currTickPtr, edfm.memFree())
+	logWrite(edfm.ctx.logs.sched, toWrite)
 	for _, p := range edfm.procQ {
 		toWrite := fmt.Sprintf("%v, dl: %.2f; \n", p.String(), p.dl)
-		logWrite(EDF_SCHED, toWrite)
+		logWrite(edfm.ctx.logs.sched, toWrite)
+	}
+	logWrite(edfm.ctx.logs.sched, "\n")
+
+	var dropped []*EDFProc
+	edfm.procQ, dropped = edfm.variant.expire(edfm.ctx, edfm.procQ, *edfm.currTickPtr)
+	for _, p := range dropped {
+		edfm.warm.release(p.p)
 	}
-	logWrite(EDF_SCHED, "\n")
 
 	totalTicksLeftToGive := Tftick(edfm.amtWorkPerTick)
 	ticksLeftPerCore := make(map[int]Tftick, 0)
@@ -79,8 +143,8 @@ func (edfm *BigEDFMachine) tick() {
 	}
 
 	ogMemFree := edfm.memFree()
-	toWrite = fmt.Sprintf("%v, %v", edfm.worldNumProcsGenPerTick, int(*edfm.currTickPtr))
-	logWrite(EDF_USAGE, toWrite)
+	toWrite = fmt.Sprintf("%v%v, %v", edfm.ctx.logs.prefix, edfm.worldNumProcsGenPerTick, int(*edfm.currTickPtr))
+	logWrite(edfm.ctx.logs.usage, toWrite)
 
 	// TODO: what if it doesn't fit?
 	putProcOnCoreWithMaxTimeLeft := func() int {
@@ -102,6 +166,8 @@ func (edfm *BigEDFMachine) tick() {
 
 	toReq := make([]*EDFProc, 0)
 
+	blocked := edfm.removeBlocked()
+
 	for len(edfm.procQ) > 0 && totalTicksLeftToGive-Tftick(TICK_SCHED_THRESHOLD) > 0.0 && len(coresWithTicksLeft) > 0 {
 
 		for i := 0; i < edfm.amtWorkPerTick; i++ {
@@ -133,33 +199,37 @@ func (edfm *BigEDFMachine) tick() {
 			}
 
 			toWrite := fmt.Sprintf("   core %v giving %v to proc %v \n", currCore, ticksLeftPerCore[currCore], procToRun.String())
-			logWrite(EDF_SCHED, toWrite)
+			logWrite(edfm.ctx.logs.sched, toWrite)
 
-			ticksUsed, done := procToRun.p.runTillOutOrDone(ticksLeftPerCore[currCore])
+			overhead := edfm.ctx.switchOverhead(procToRun.p, 0, currCore, edfm.coreHist, ticksLeftPerCore[currCore])
+			ticksUsed, done := procToRun.p.runAtSpeed(ticksLeftPerCore[currCore]-overhead, edfm.coreSpeeds[currCore])
 
-			ticksLeftPerCore[currCore] -= ticksUsed
-			totalTicksLeftToGive -= ticksUsed
+			ticksLeftPerCore[currCore] -= ticksUsed + overhead
+			totalTicksLeftToGive -= ticksUsed + overhead
 
 			if ticksLeftPerCore[currCore] < Tftick(TICK_SCHED_THRESHOLD) {
 				delete(coresWithTicksLeft, currCore)
 			}
 
 			if !done {
+				procToRun.p.blockIfAtIO(*edfm.currTickPtr + (1 - ticksLeftPerCore[currCore]))
 				toReq = append(toReq, procToRun)
 			} else {
 				// if the proc is done, update the ticksPassed to be exact for metrics etc
 				procToRun.p.timeDone = *edfm.currTickPtr + (1 - ticksLeftPerCore[currCore])
+				edfm.ctx.procDone(procToRun.p)
+				edfm.warm.release(procToRun.p)
 
 				toWrite := fmt.Sprintf("   -> done: %v\n", procToRun.String())
-				logWrite(EDF_SCHED, toWrite)
+				logWrite(edfm.ctx.logs.sched, toWrite)
 
-				if (procToRun.p.timeDone - procToRun.p.timeStarted) > procToRun.p.compDone {
+				if procToRun.p.timeDone > procToRun.p.deadline() {
 					toWrite := fmt.Sprintf("   ---> OVER %v \n", procToRun.String())
-					logWrite(EDF_SCHED, toWrite)
+					logWrite(edfm.ctx.logs.sched, toWrite)
 				}
 
-				toWrite = fmt.Sprintf("%v, %v, %v, %v \n", edfm.worldNumProcsGenPerTick, procToRun.p.willingToSpend(), (procToRun.p.timeDone - procToRun.p.timeStarted).String(), procToRun.p.compDone.String())
-				logWrite(EDF_PROCS_DONE, toWrite)
+				toWrite = fmt.Sprintf("%v%v, %v, %v, %v \n", edfm.ctx.logs.prefix, edfm.worldNumProcsGenPerTick, procToRun.p.willingToSpend(), (procToRun.p.timeDone - procToRun.p.timeStarted).String(), procToRun.p.compDone.String())
+				logWrite(edfm.ctx.logs.procsDone, toWrite)
 			}
 
 		}
@@ -169,71 +239,68 @@ func (edfm *BigEDFMachine) tick() {
 	for _, p := range toReq {
 		edfm.enq(p)
 	}
+	for _, p := range blocked {
+		edfm.enq(p)
+	}
+
+	edfm.checkOOM()
 
 	toWrite = fmt.Sprintf("cores with ticks left: %v, ticks left over: %v\n", coresWithTicksLeft, ticksLeftPerCore)
-	logWrite(EDF_SCHED, toWrite)
+	logWrite(edfm.ctx.logs.sched, toWrite)
 
 	if totalTicksLeftToGive < 0.00002 {
 		totalTicksLeftToGive = 0
 	}
 	toWrite = fmt.Sprintf(", %.3f, %v\n", float64(math.Copysign(float64(totalTicksLeftToGive), 1)), ogMemFree)
-	logWrite(EDF_USAGE, toWrite)
+	logWrite(edfm.ctx.logs.usage, toWrite)
+
+	edfm.ctx.machineTicked(edfm.power, edfm.amtWorkPerTick, totalTicksLeftToGive, physMemUsed(edfm.procs())+edfm.warm.mem())
 }
 
 func (edfm *BigEDFMachine) deq() *EDFProc {
-	minDl := float32(math.MaxFloat32)
 	var procToRet *EDFProc
-	idxToDel := -1
-
-	for i, p := range edfm.procQ {
-		if p.dl < minDl {
-			minDl = p.dl
-			procToRet = p
-			idxToDel = i
-		}
-	}
-
-	if idxToDel >= 0 {
-		edfm.procQ = append(edfm.procQ[:idxToDel], edfm.procQ[idxToDel+1:]...)
-	}
-
+	procToRet, edfm.procQ = edfm.variant.deq(edfm.procQ)
 	return procToRet
 }
 
-func (edfm *BigEDFMachine) enq(newProc *EDFProc) {
-
-	edfm.procQ = append(edfm.procQ, newProc)
-}
-
-func (edfm *BigEDFMachine) checkKill(newProc *EDFProc) (Tid, float32) {
+func (edfm *BigEDFMachine) removeBlocked() []*EDFProc {
 
-	minMoneyThrownAway := float32(math.MaxFloat32)
-	procId := Tid(-1)
+	runnable := make([]*EDFProc, 0, len(edfm.procQ))
+	blocked := make([]*EDFProc, 0)
 
 	for _, p := range edfm.procQ {
-		if (p.p.maxMem() > newProc.p.maxMem()) && (p.p.willingToSpend() < newProc.p.willingToSpend()) {
-			wldThrow := float32(float32(p.p.compDone) * p.p.willingToSpend())
-			if wldThrow < minMoneyThrownAway {
-				procId = p.p.procId
-				minMoneyThrownAway = wldThrow
-			}
+		if p.p.runnable(*edfm.currTickPtr) {
+			runnable = append(runnable, p)
+		} else {
+			blocked = append(blocked, p)
 		}
 	}
 
-	return procId, minMoneyThrownAway
+	edfm.procQ = runnable
+
+	return blocked
+}
+
+func (edfm *BigEDFMachine) enq(newProc *EDFProc) {
 
+	edfm.procQ = append(edfm.procQ, newProc)
 }
 
-func (edfm *BigEDFMachine) kill(pid Tid) {
+func (edfm *BigEDFMachine) kill(pid Tid) *EDFProc {
 
 	tmp := make([]*EDFProc, 0)
+	var killed *EDFProc
 
 	for _, currProc := range edfm.procQ {
 		if currProc.p.procId != pid {
 			tmp = append(tmp, currProc)
+		} else {
+			killed = currProc
 		}
 	}
 
 	edfm.procQ = tmp
 
+	return killed
+
 }
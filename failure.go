@@ -0,0 +1,140 @@
+package slasched
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// a failure that is scripted to happen no matter what the MTBFs say; either a whole rack or a list
+// of machines
+type FailureEvent struct {
+	atTick   int
+	rack     int // -1 if not a rack failure
+	machines []Tid
+	duration Tftick
+}
+
+var scriptedFailures = []FailureEvent{}
+
+// decides which machines are down when. Every LB gets its own copy, seeded the same, so that they
+// all see the same failures as long as they have the same machines
+type FailureModel struct {
+	rand      *rand.Rand
+	downSince map[Tid]Tftick
+	downUntil map[Tid]Tftick
+	lostAt    map[*Proc]Tftick // procs lost to a failure that haven't been placed again yet
+}
+
+func newFailureModel() *FailureModel {
+	return &FailureModel{
+		rand:      rand.New(rand.NewSource(SEED)),
+		downSince: make(map[Tid]Tftick),
+		downUntil: make(map[Tid]Tftick),
+		lostAt:    make(map[*Proc]Tftick),
+	}
+}
+
+func (fm *FailureModel) isDown(mid Tid) bool {
+	_, ok := fm.downUntil[mid]
+	return ok
+}
+
+func (fm *FailureModel) takeDown(mid Tid, now Tftick, duration Tftick, failed *[]Tid) {
+	if fm.isDown(mid) {
+		return
+	}
+	fm.downSince[mid] = now
+	fm.downUntil[mid] = now + duration
+	*failed = append(*failed, mid)
+}
+
+// the machines that fail and that come back at the start of this tick
+func (fm *FailureModel) tick(now Tftick, mids []Tid) ([]Tid, []Tid) {
+
+	failed := make([]Tid, 0)
+	recovered := make([]Tid, 0)
+
+	// go through the machines in order so the random draws line up across LBs
+	sorted := make([]Tid, len(mids))
+	copy(sorted, mids)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for _, mid := range sorted {
+		if until, ok := fm.downUntil[mid]; ok && until <= now {
+			delete(fm.downUntil, mid)
+			recovered = append(recovered, mid)
+		}
+	}
+
+	for _, ev := range scriptedFailures {
+		if ev.atTick != int(now) {
+			continue
+		}
+		for _, mid := range sorted {
+			if (ev.rack >= 0 && int(mid)/RACK_SIZE == ev.rack) || containsTid(ev.machines, mid) {
+				fm.takeDown(mid, now, ev.duration, &failed)
+			}
+		}
+	}
+
+	if RACK_MTBF > 0 {
+		nRacks := 0
+		if len(sorted) > 0 {
+			nRacks = int(sorted[len(sorted)-1])/RACK_SIZE + 1
+		}
+		for rack := 0; rack < nRacks; rack++ {
+			if fm.rand.Float64()*RACK_MTBF >= 1 {
+				continue
+			}
+			duration := Tftick(fm.rand.ExpFloat64() * RACK_MTTR)
+			for _, mid := range sorted {
+				if int(mid)/RACK_SIZE == rack {
+					fm.takeDown(mid, now, duration, &failed)
+				}
+			}
+		}
+	}
+
+	if MACHINE_MTBF > 0 {
+		for _, mid := range sorted {
+			// always draw both, so that one machine being down doesn't shift everyone else's draws
+			fails := fm.rand.Float64()*MACHINE_MTBF < 1
+			duration := Tftick(fm.rand.ExpFloat64() * MACHINE_MTTR)
+			if fails {
+				fm.takeDown(mid, now, duration, &failed)
+			}
+		}
+	}
+
+	return failed, recovered
+}
+
+// called by an LB for every proc that was on a machine when it failed. The proc starts over, and the
+// LB hands it back to whichever GS (or scheduler, or dispatcher) placed it
+func (ctx *LBCtx) procLost(p *Proc) {
+	ctx.stats.inc("procs_lost", 1)
+	ctx.stats.inc("failure_lost_work", float64(p.compDone))
+
+	ctx.failures.lostAt[p] = *ctx.currTickPtr
+	p.restart()
+}
+
+func (ctx *LBCtx) machineFailed() {
+	ctx.stats.inc("machine_failures", 1)
+}
+
+func (ctx *LBCtx) machineRecovered(mid Tid) {
+	ctx.stats.inc("machine_downtime", float64(*ctx.currTickPtr-ctx.failures.downSince[mid]))
+	delete(ctx.failures.downSince, mid)
+}
+
+// called by the machines whenever they get a new proc
+func (ctx *LBCtx) procPlaced(p *Proc) {
+	ctx.stats.inc("procs_placed", 1)
+
+	if lostAt, ok := ctx.failures.lostAt[p]; ok {
+		ctx.stats.inc("procs_recovered", 1)
+		ctx.stats.inc("recovery_latency", float64(*ctx.currTickPtr-lostAt))
+		delete(ctx.failures.lostAt, p)
+	}
+}
@@ -0,0 +1,89 @@
+package slasched
+
+// a set of identical machines within a fleet
+type MachineClass struct {
+	name     string
+	count    int
+	numCores int
+	mem      Tmem
+	speed    float64 // ticks of compute a core does per tick, relative to the baseline
+}
+
+type FleetSpec []MachineClass
+
+func uniformFleet(numMachines int, numCores int) FleetSpec {
+	return FleetSpec{
+		{name: "default", count: numMachines, numCores: numCores, mem: MEM_PER_MACHINE, speed: 1},
+	}
+}
+
+// same total cores and memory as the uniform fleet, but half of it is in memory-heavy machines and
+// half in cpu-heavy ones with faster cores
+func mixedFleet(numMachines int, numCores int) FleetSpec {
+	return FleetSpec{
+		{name: "mem-heavy", count: numMachines / 2, numCores: numCores / 2, mem: MEM_PER_MACHINE * 3 / 2, speed: 1},
+		{name: "cpu-heavy", count: numMachines - numMachines/2, numCores: numCores * 3 / 2, mem: MEM_PER_MACHINE / 2, speed: 1.25},
+	}
+}
+
+func (f FleetSpec) numMachines() int {
+	n := 0
+	for _, mc := range f {
+		n += mc.count
+	}
+	return n
+}
+
+func (f FleetSpec) totalMem() Tmem {
+	mem := Tmem(0)
+	for _, mc := range f {
+		mem += Tmem(mc.count) * mc.mem
+	}
+	return mem
+}
+
+// how much work the whole fleet can do in a tick
+func (f FleetSpec) totalCompute() float64 {
+	compute := 0.0
+	for _, mc := range f {
+		compute += float64(mc.count*mc.numCores) * mc.speed
+	}
+	return compute
+}
+
+// the class of every machine in the fleet, indexed by machine id
+func (f FleetSpec) machineClasses() []MachineClass {
+	classes := make([]MachineClass, 0, f.numMachines())
+	for _, mc := range f {
+		for i := 0; i < mc.count; i++ {
+			classes = append(classes, mc)
+		}
+	}
+	return classes
+}
+
+// which class the autoscaler should add a machine of next, given how many of each class it has
+// added so far; new machines keep to the same mix of classes as the fleet started out with
+func (f FleetSpec) classToAdd(added []int) int {
+	best := 0
+	for i, mc := range f {
+		if mc.count == 0 {
+			continue
+		}
+		if f[best].count == 0 || float64(added[i])/float64(mc.count) < float64(added[best])/float64(f[best].count) {
+			best = i
+		}
+	}
+	return best
+}
+
+// the speed of every core in the fleet, for the LBs that pool all of them together
+func (f FleetSpec) coreSpeeds() []float64 {
+	speeds := make([]float64, 0)
+	for _, mc := range f {
+		for i := 0; i < mc.count*mc.numCores; i++ {
+			speeds = append(speeds, mc.speed)
+		}
+	}
+	return speeds
+}
@@ -0,0 +1,20 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// the autoscaler should grow a mixed fleet in the same proportions it started out with
+func TestClassToAddKeepsMix(t *testing.T) {
+	fleet := FleetSpec{
+		{name: "small", count: 3, numCores: 4, mem: MEM_PER_MACHINE, speed: 1},
+		{name: "big", count: 1, numCores: 16, mem: MEM_PER_MACHINE, speed: 1},
+	}
+	added := make([]int, len(fleet))
+	for i := 0; i < 8; i++ {
+		added[fleet.classToAdd(added)] += 1
+	}
+	if added[0] != 6 || added[1] != 2 {
+		t.Fatalf("added %v of each class, want [6 2]", added)
+	}
+}
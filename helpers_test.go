@@ -0,0 +1,22 @@
+package slasched
+
+// a proc that started at tick 0, needs comp ticks of compute and is guessed to, and pays price
+func newTestProc(id Tid, comp float32, price float32, mem int) *Proc {
+	return newProvProc(id, 0, newPrivProc(comp, comp, price, mem))
+}
+
+// wires nodes up into a DAG that started at start, nodes[0] being the root; every edge goes from
+// the parent's index to the child's
+func newTestDAG(start Tftick, nodes []*ProcInternals, edges [][2]int) *DAG {
+	dag := &DAG{nodes: nodes, timeStarted: start}
+	for _, pi := range nodes {
+		pi.dag = dag
+	}
+	for _, e := range edges {
+		parent, child := nodes[e[0]], nodes[e[1]]
+		parent.children = append(parent.children, child)
+		child.nParents += 1
+	}
+	setCritPath(nodes[0])
+	return dag
+}
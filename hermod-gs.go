@@ -11,9 +11,17 @@ type HermodGS struct {
 	procQ           []*Proc
 	currTickPtr     *Tftick
 	nProcGenPerTick int
+	ctx             *LBCtx
+	policy          LocalPolicy // that the machines run
+
+	// for HERMOD_HYBRID: an EWMA of how loaded the partition is, and whether that is high enough to
+	// balance load instead of packing. Both start off from the first load the GS sees
+	loadEst  float64
+	highLoad bool
+	seeded   bool
 }
 
-func newHermodGS(id Tid, machines map[Tid]*HermodMachine, currTickPtr *Tftick, nProcGenPerTick int) *HermodGS {
+func newHermodGS(id Tid, machines map[Tid]*HermodMachine, currTickPtr *Tftick, nProcGenPerTick int, ctx *LBCtx, policy LocalPolicy) *HermodGS {
 
 	hgs := &HermodGS{
 		gsId:            id,
@@ -21,56 +29,152 @@ func newHermodGS(id Tid, machines map[Tid]*HermodMachine, currTickPtr *Tftick, n
 		procQ:           make([]*Proc, 0),
 		currTickPtr:     currTickPtr,
 		nProcGenPerTick: nProcGenPerTick,
+		ctx:             ctx,
+		policy:          policy,
 	}
 	return hgs
 }
 
 func (hgs *HermodGS) placeProcs() {
 
-	logWrite(HERMOD_SCHED, "\n")
+	logWrite(hgs.ctx.logs.sched, "\n")
+
+	if HERMOD_HYBRID {
+		hgs.updateMode()
+	}
 
 	toReq := make([]*Proc, 0)
 
-	for _, p := range hgs.procQ {
+	// anything that comes back while we're placing goes in the new procQ
+	procsToPlace := hgs.procQ
+	hgs.procQ = make([]*Proc, 0)
+
+	for _, p := range procsToPlace {
 		// place given proc
 
-		machineToUse := hgs.pickMachine(p)
+		machineToUse, probeTime := hgs.pickMachine(p)
 
 		toWrite := fmt.Sprintf("%v, GS %v placing proc %v \n", int(*hgs.currTickPtr), hgs.gsId, p.procId)
-		logWrite(HERMOD_SCHED, toWrite)
+		logWrite(hgs.ctx.logs.sched, toWrite)
 
 		if machineToUse == nil {
-			logWrite(HERMOD_SCHED, "    -> nothing avail \n")
+			logWrite(hgs.ctx.logs.sched, "    -> nothing avail \n")
 			toReq = append(toReq, p)
 			continue
 		}
 
-		machineToUse.placeProc(p)
+		hgs.sendPlacement(machineToUse, p, probeTime)
 		toWrite = fmt.Sprintf("    -> chose %v \n", machineToUse.machineId)
-		logWrite(HERMOD_SCHED, toWrite)
+		logWrite(hgs.ctx.logs.sched, toWrite)
 
 	}
 
-	hgs.procQ = toReq
+	hgs.procQ = append(hgs.procQ, toReq...)
 }
 
-func (hgs *HermodGS) pickMachine(procToPlace *Proc) *HermodMachine {
+// if the placement doesn't make it, or the machine left the partition while it was on its way,
+// the proc comes back to the GS
+func (hgs *HermodGS) sendPlacement(machineToUse *HermodMachine, p *Proc, after Tftick) {
 
-	// power of k choices - do hermod hybrid load balancing thing among the sampled machines
+	place := func() {
+		if _, ok := hgs.machines[machineToUse.machineId]; !ok {
+			hgs.procQ = append(hgs.procQ, p)
+			return
+		}
+		p.gsId = hgs.gsId
+		machineToUse.placeProc(p)
+	}
 
-	// right now always doing high load scenario stuff
+	hgs.ctx.net.send(MSG_PLACE, after, place, func() { hgs.procQ = append(hgs.procQ, p) })
+}
+
+// the load of the partition is how many procs there are per unit of compute, across all of its
+// machines. Hysteresis keeps the GS from flapping between modes when the load sits near a threshold
+func (hgs *HermodGS) updateMode() {
+
+	nProcs, compute := 0.0, 0.0
+	for _, m := range hgs.machines {
+		nProcs += float64(len(m.procQ))
+		compute += float64(m.numCores) * m.speed
+	}
+	if compute > 0 {
+		load := nProcs / compute
+		if hgs.seeded {
+			load = HERMOD_LOAD_ALPHA*load + (1-HERMOD_LOAD_ALPHA)*hgs.loadEst
+		}
+		hgs.loadEst = load
+	}
+
+	// the first estimate just picks the mode to start in, it isn't a switch
+	if !hgs.seeded {
+		hgs.seeded = true
+		hgs.highLoad = hgs.loadEst > HERMOD_HIGH_LOAD
+	} else if hgs.highLoad && hgs.loadEst < HERMOD_LOW_LOAD {
+		hgs.highLoad = false
+		hgs.ctx.stats.inc("hermod_mode_switches", 1)
+	} else if !hgs.highLoad && hgs.loadEst > HERMOD_HIGH_LOAD {
+		hgs.highLoad = true
+		hgs.ctx.stats.inc("hermod_mode_switches", 1)
+	}
+
+	if hgs.highLoad {
+		hgs.ctx.stats.inc("hermod_ticks_balancing", 1)
+	} else {
+		hgs.ctx.stats.inc("hermod_ticks_packing", 1)
+	}
+}
+
+// returns the machine to use, and how long the GS spent waiting on probes to pick it
+func (hgs *HermodGS) pickMachine(procToPlace *Proc) (*HermodMachine, Tftick) {
+
+	// power of k choices - do hermod hybrid load balancing thing among the sampled machines: at high
+	// load pick the least loaded one, at low load pack, ie pick the most loaded one that still has a
+	// core for the proc (and the least loaded one if none do)
 
 	var machineToUse *HermodMachine
 	machinesToTry := pickRandomElements(Values(hgs.machines), K_CHOICES_DOWN)
-	leastNumProcs := math.MaxInt
+	leastLoad := math.MaxFloat64
+	foundWarm := false
+	foundFeasible := false
+	probeTime := Tftick(0)
+
+	var packOn *HermodMachine
+	mostLoad := -1.0
+	packWarm := false
 
+	// for distributed EDF, machines where the proc can still make its deadline win. Then machines
+	// where the proc's function is warm, then the least (or most) loaded one
 	for _, m := range machinesToTry {
-		if len(m.procQ) < leastNumProcs && m.memFree() > procToPlace.maxMem() {
-			leastNumProcs = len(m.procQ)
+		ans, answered, rtt := probe(hgs.ctx.net, func() HermodProbe { return m.probeAnswer(procToPlace) })
+		if !answered {
+			continue
+		}
+		probeTime = Tftick(math.Max(float64(probeTime), float64(rtt)))
+
+		if ans.memFree <= procToPlace.maxMem() {
+			continue
+		}
+		if (ans.feasible && !foundFeasible) || (ans.feasible == foundFeasible && ((ans.warm && !foundWarm) || (ans.warm == foundWarm && ans.load < leastLoad))) {
+			leastLoad = ans.load
 			machineToUse = m
+			foundWarm = ans.warm
+			foundFeasible = ans.feasible
+		}
+		if !hgs.highLoad && ans.nProcs < m.numCores && ans.feasible {
+			if (ans.warm && !packWarm) || (ans.warm == packWarm && ans.load > mostLoad) {
+				mostLoad = ans.load
+				packOn = m
+				packWarm = ans.warm
+			}
 		}
 	}
 
-	return machineToUse
+	if packOn != nil {
+		return packOn, probeTime
+	}
+	if machineToUse != nil && !foundFeasible {
+		hgs.ctx.stats.inc("edf_infeasible_placements", 1)
+	}
+	return machineToUse, probeTime
 
 }
@@ -0,0 +1,69 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// a GS that starts out on an idle partition should start out packing, not count a switch to it
+func TestHermodGSStartsInMode(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 8)
+	machines := map[Tid]*HermodMachine{0: newHermodMachine(0, 8, MEM_PER_MACHINE, 1, &currTick, 0, ctx, LOCAL_PS)}
+	hgs := newHermodGS(0, machines, &currTick, 0, ctx, LOCAL_PS)
+
+	hgs.updateMode()
+	if hgs.highLoad {
+		t.Fatalf("balancing load on an idle partition")
+	}
+	if switches := ctx.stats.get("hermod_mode_switches"); switches != 0 {
+		t.Fatalf("%v mode switches before the load changed", switches)
+	}
+
+	for i := 0; i < 16; i++ {
+		machines[0].placeProc(newTestProc(Tid(i), 10, 1, 100))
+	}
+	for i := 0; i < 10; i++ {
+		hgs.updateMode()
+	}
+	if !hgs.highLoad || ctx.stats.get("hermod_mode_switches") != 1 {
+		t.Fatalf("didn't switch to balancing once the partition got loaded")
+	}
+}
+
+// for distributed EDF the GS sends a proc to a machine where it can still make its deadline, even if
+// that machine is more loaded; if there is none it goes to the least loaded one, and that counts
+func TestHermodGSPicksFeasible(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 2)
+
+	withDeadline := func(id Tid, comp float32, dl Tftick) *Proc {
+		p := newTestProc(id, comp, 1, 100)
+		p.procInternals.slaDeadline = dl
+		return p
+	}
+
+	// lots of work, but all of it due after the new proc
+	lateWork := newHermodMachine(0, 1, MEM_PER_MACHINE, 1, &currTick, 0, ctx, LOCAL_EDF)
+	lateWork.placeProc(withDeadline(0, 10, 100))
+	lateWork.placeProc(withDeadline(1, 10, 100))
+	// less work, but it is due first
+	earlyWork := newHermodMachine(1, 1, MEM_PER_MACHINE, 1, &currTick, 0, ctx, LOCAL_EDF)
+	earlyWork.placeProc(withDeadline(2, 10, 4))
+
+	p := withDeadline(3, 2, 5)
+	if !lateWork.deadlineFeasible(p) || earlyWork.deadlineFeasible(p) {
+		t.Fatalf("proc feasible on the machine with late work: %v, on the one with early work: %v, want only the first", lateWork.deadlineFeasible(p), earlyWork.deadlineFeasible(p))
+	}
+
+	hgs := newHermodGS(0, map[Tid]*HermodMachine{0: lateWork, 1: earlyWork}, &currTick, 0, ctx, LOCAL_EDF)
+	hgs.highLoad = true
+	if m, _ := hgs.pickMachine(p); m != lateWork {
+		t.Fatalf("picked machine %v, want the one where the proc makes its deadline", m.machineId)
+	}
+
+	hgs = newHermodGS(0, map[Tid]*HermodMachine{1: earlyWork}, &currTick, 0, ctx, LOCAL_EDF)
+	hgs.highLoad = true
+	if m, _ := hgs.pickMachine(p); m != earlyWork || ctx.stats.get("edf_infeasible_placements") != 1 {
+		t.Fatalf("no feasible machine, but the placement wasn't counted as infeasible")
+	}
+}
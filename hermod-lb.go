@@ -1,47 +1,72 @@
 package slasched
 
+import (
+	"sort"
+)
+
 type HermodLB struct {
 	currTickPtr     *Tftick
+	ctx             *LBCtx
 	nProcGenPerTick int
 	machines        map[Tid]*HermodMachine
 	GSSs            []*HermodGS
 	roundRobinInd   int
+	failedFrom      map[Tid]*HermodGS // failed machines, and whose partition they were in (nil if they were draining)
+
+	// for the autoscaler
+	fleet         FleetSpec
+	nGenPerTick   int
+	nextMachineId Tid
+	classesAdded  []int // per class in fleet, how many machines the autoscaler added
+	draining      map[Tid]bool
+
+	policy LocalPolicy // how the machines run their procs
 }
 
-func newHermodLB(numMachines int, numCores int, nGenPerTick int, nGSSs int, currTickPtr *Tftick) *HermodLB {
+// also does distributed SRPT and EDF, with each machine running its own procs in that order
+func newHermodLB(fleet FleetSpec, nGenPerTick int, nGSSs int, currTickPtr *Tftick, ctx *LBCtx, policy LocalPolicy) *HermodLB {
 
 	mlb := &HermodLB{
 		currTickPtr:   currTickPtr,
+		ctx:           ctx,
 		machines:      map[Tid]*HermodMachine{},
 		GSSs:          make([]*HermodGS, nGSSs),
 		roundRobinInd: 0,
+		failedFrom:    map[Tid]*HermodGS{},
+		fleet:         fleet,
+		classesAdded:  make([]int, len(fleet)),
+		nGenPerTick:   nGenPerTick,
+		draining:      map[Tid]bool{},
+		policy:        policy,
 	}
 
-	for i := 0; i < numMachines; i++ {
+	ctx.enqProc = mlb.enqProc
+
+	for i, mc := range fleet.machineClasses() {
 		mid := Tid(i)
-		mlb.machines[Tid(i)] = newHermodMachine(mid, numCores, MEM_PER_MACHINE, mlb.currTickPtr, nGenPerTick)
+		mlb.machines[Tid(i)] = newHermodMachine(mid, mc.numCores, mc.mem, mc.speed, mlb.currTickPtr, nGenPerTick, mlb.ctx, policy)
 	}
 
-	numMachinesPerGS := int(numMachines / nGSSs)
-	currBeg := Tid(0)
+	// machine ids are grouped by class, so dealing them out in turn gives every GS its share of each class
+	machinesForGSSs := make([]map[Tid]*HermodMachine, nGSSs)
 	for i := 0; i < nGSSs; i++ {
-		currEnd := currBeg + Tid(numMachinesPerGS)
-		if currEnd > Tid(numMachines)-Tid(numMachinesPerGS) {
-			currEnd = Tid(numMachines)
-		}
-		machinesForGSS := make(map[Tid]*HermodMachine, 0)
-		for id, m := range mlb.machines {
-			if id >= currBeg && id < currEnd {
-				machinesForGSS[id] = m
-			}
-		}
-		mlb.GSSs[i] = newHermodGS(Tid(i), machinesForGSS, mlb.currTickPtr, mlb.nProcGenPerTick)
-		currBeg = currEnd
+		machinesForGSSs[i] = make(map[Tid]*HermodMachine, 0)
 	}
+	for id, m := range mlb.machines {
+		machinesForGSSs[int(id)%nGSSs][id] = m
+	}
+	for i := 0; i < nGSSs; i++ {
+		mlb.GSSs[i] = newHermodGS(Tid(i), machinesForGSSs[i], mlb.currTickPtr, mlb.nProcGenPerTick, ctx, policy)
+	}
+	mlb.nextMachineId = Tid(len(mlb.machines))
 
 	return mlb
 }
 
+func (hlb *HermodLB) getCtx() *LBCtx {
+	return hlb.ctx
+}
+
 func (hlb *HermodLB) enqProc(proc *Proc) {
 
 	// I think this is fine; in 4.3 they basically say it works
@@ -56,6 +81,8 @@ func (hlb *HermodLB) enqProc(proc *Proc) {
 
 func (hlb *HermodLB) placeProcs() {
 
+	hlb.ctx.net.deliver(*hlb.currTickPtr)
+
 	for _, gs := range hlb.GSSs {
 		gs.placeProcs()
 	}
@@ -63,7 +90,109 @@ func (hlb *HermodLB) placeProcs() {
 }
 
 func (hlb *HermodLB) tick() {
-	for _, m := range hlb.machines {
+
+	failed, recovered := hlb.ctx.failures.tick(*hlb.currTickPtr, Keys(hlb.machines))
+	for _, mid := range recovered {
+		// draining machines weren't in a partition when they failed, so they don't go back into one;
+		// they are empty now, and get removed once they tick again
+		if gs, ok := hlb.failedFrom[mid]; ok {
+			if gs != nil {
+				gs.machines[mid] = hlb.machines[mid]
+			}
+			delete(hlb.failedFrom, mid)
+		}
+		hlb.ctx.machineRecovered(mid)
+	}
+	for _, mid := range failed {
+		hlb.failMachine(mid)
+	}
+
+	if AUTOSCALE {
+		hlb.autoscale()
+	}
+
+	for mid, m := range hlb.machines {
+		if _, ok := hlb.failedFrom[mid]; ok {
+			continue
+		}
 		m.tick()
+		if hlb.draining[mid] && len(m.procQ) == 0 {
+			delete(hlb.draining, mid)
+			delete(hlb.machines, mid)
+		}
+	}
+}
+
+func (hlb *HermodLB) autoscale() {
+
+	coresIdle, cores, backlog := 0.0, 0.0, 0
+	active := make([]*HermodMachine, 0, len(hlb.machines))
+	for _, gs := range hlb.GSSs {
+		for _, m := range gs.machines {
+			active = append(active, m)
+			coresIdle += float64(m.lastTicksIdle)
+			cores += float64(m.numCores)
+		}
+		backlog += len(gs.procQ)
+	}
+
+	util := 1.0
+	if cores > 0 {
+		util = 1 - coresIdle/cores
+	}
+	nReady, nToDrain := hlb.ctx.autoscaler.tick(hlb.ctx, util, backlog, len(active), len(hlb.draining))
+
+	// new machines keep to the mix of classes in the fleet, and go to whichever GS has the fewest
+	for i := 0; i < nReady; i++ {
+		class := hlb.fleet.classToAdd(hlb.classesAdded)
+		hlb.classesAdded[class] += 1
+		mc := hlb.fleet[class]
+		m := newHermodMachine(hlb.nextMachineId, mc.numCores, mc.mem, mc.speed, hlb.currTickPtr, hlb.nGenPerTick, hlb.ctx, hlb.policy)
+		hlb.machines[m.machineId] = m
+		hlb.nextMachineId += 1
+
+		smallest := hlb.GSSs[0]
+		for _, gs := range hlb.GSSs {
+			if len(gs.machines) < len(smallest.machines) {
+				smallest = gs
+			}
+		}
+		smallest.machines[m.machineId] = m
+	}
+
+	// drain the emptiest machines: take them out of their partition so they stop getting procs, but
+	// keep ticking them until what is on them is done
+	sort.Slice(active, func(i, j int) bool {
+		if active[i].load() == active[j].load() {
+			return active[i].machineId < active[j].machineId
+		}
+		return active[i].load() < active[j].load()
+	})
+	for i := 0; i < nToDrain && i < len(active); i++ {
+		mid := active[i].machineId
+		for _, gs := range hlb.GSSs {
+			delete(gs.machines, mid)
+		}
+		hlb.draining[mid] = true
+	}
+}
+
+// take the machine out of its GS's partition until it recovers
+func (hlb *HermodLB) failMachine(mid Tid) {
+	hlb.ctx.machineFailed()
+
+	if hlb.draining[mid] {
+		hlb.failedFrom[mid] = nil
+	}
+	for _, gs := range hlb.GSSs {
+		if _, ok := gs.machines[mid]; ok {
+			delete(gs.machines, mid)
+			hlb.failedFrom[mid] = gs
+		}
+	}
+
+	for _, p := range hlb.machines[mid].fail() {
+		hlb.ctx.procLost(p)
+		hlb.GSSs[p.gsId].procQ = append(hlb.GSSs[p.gsId].procQ, p)
 	}
 }
@@ -0,0 +1,76 @@
+package slasched
+
+import (
+	"testing"
+)
+
+func newFailureTestLB(currTick *Tftick, failAt int, duration Tftick) *HermodLB {
+	fleet := uniformFleet(2, 2)
+	ctx := newLBCtx("test", 0, currTick, fleet.totalCompute())
+	scriptedFailures = []FailureEvent{{atTick: failAt, rack: -1, machines: []Tid{0}, duration: duration}}
+	return newHermodLB(fleet, 0, 1, currTick, ctx, LOCAL_PS)
+}
+
+// a failed machine loses its procs to the GS, sits out until it recovers, and then goes back into
+// its partition
+func TestHermodFailAndRecover(t *testing.T) {
+	defer func() { scriptedFailures = []FailureEvent{} }()
+
+	currTick := Tftick(1)
+	hlb := newFailureTestLB(&currTick, 1, 2)
+	gs := hlb.GSSs[0]
+
+	p := newTestProc(0, 10, 1, 100)
+	p.gsId = gs.gsId
+	hlb.machines[0].placeProc(p)
+
+	hlb.tick()
+	if _, ok := gs.machines[0]; ok {
+		t.Fatalf("failed machine still in its partition")
+	}
+	if len(gs.procQ) != 1 || p.compDone != 0 {
+		t.Fatalf("proc on the failed machine didn't go back to its GS to start over")
+	}
+
+	currTick = 3
+	hlb.tick()
+	if _, ok := gs.machines[0]; !ok {
+		t.Fatalf("recovered machine didn't go back into its partition")
+	}
+	if downtime := hlb.ctx.stats.get("machine_downtime"); downtime != 2 {
+		t.Fatalf("%v ticks of downtime, want 2", downtime)
+	}
+}
+
+// a machine that fails while draining recovers into the drained set, and then goes away like any
+// other drained machine, without anything of the failure left behind
+func TestHermodFailWhileDraining(t *testing.T) {
+	defer func() { scriptedFailures = []FailureEvent{} }()
+
+	currTick := Tftick(1)
+	hlb := newFailureTestLB(&currTick, 1, 2)
+	gs := hlb.GSSs[0]
+
+	p := newTestProc(0, 10, 1, 100)
+	p.gsId = gs.gsId
+	hlb.machines[0].placeProc(p)
+	delete(gs.machines, 0)
+	hlb.draining[0] = true
+
+	hlb.tick()
+	currTick = 3
+	hlb.tick()
+
+	if _, ok := hlb.machines[0]; ok {
+		t.Fatalf("drained machine wasn't removed after it recovered")
+	}
+	if _, ok := gs.machines[0]; ok {
+		t.Fatalf("drained machine went back into a partition")
+	}
+	if len(hlb.failedFrom) != 0 || len(hlb.ctx.failures.downSince) != 0 || len(hlb.ctx.failures.downUntil) != 0 {
+		t.Fatalf("failure bookkeeping left behind for a removed machine")
+	}
+	if downtime := hlb.ctx.stats.get("machine_downtime"); downtime != 2 {
+		t.Fatalf("%v ticks of downtime, want 2", downtime)
+	}
+}
@@ -9,19 +9,32 @@ type HermodMachine struct {
 	machineId               Tid
 	currTickPtr             *Tftick
 	numCores                int
+	speed                   float64
 	procQ                   []*Proc
 	totalMem                Tmem
 	worldNumProcsGenPerTick int
+	ctx                     *LBCtx
+	lastTicksIdle           Tftick
+	coreHist                CoreHistory
+	warm                    *WarmCache
+	power                   *PowerState
+	policy                  LocalPolicy
 }
 
-func newHermodMachine(mid Tid, numCores int, totMem Tmem, currTickPtr *Tftick, worldNumProcsGenPerTick int) *HermodMachine {
+func newHermodMachine(mid Tid, numCores int, totMem Tmem, speed float64, currTickPtr *Tftick, worldNumProcsGenPerTick int, ctx *LBCtx, policy LocalPolicy) *HermodMachine {
 	return &HermodMachine{
 		machineId:               mid,
 		currTickPtr:             currTickPtr,
 		numCores:                numCores,
+		speed:                   speed,
 		procQ:                   make([]*Proc, 0),
 		totalMem:                totMem,
 		worldNumProcsGenPerTick: worldNumProcsGenPerTick,
+		ctx:                     ctx,
+		coreHist:                CoreHistory{},
+		warm:                    newWarmCache(totMem),
+		power:                   newPowerState(),
+		policy:                  policy,
 	}
 
 }
@@ -30,22 +43,76 @@ func (hm *HermodMachine) memFree() Tmem {
 	currMemUsed := Tmem(0)
 
 	for _, p := range hm.procQ {
-		currMemUsed += p.maxMem()
+		currMemUsed += p.memCharged()
 	}
 
-	return hm.totalMem - currMemUsed
+	return hm.totalMem - currMemUsed - hm.warm.mem()
+}
+
+func (hm *HermodMachine) checkOOM() {
+	for physMemUsed(hm.procQ)+hm.warm.mem() > hm.totalMem {
+		if hm.warm.drop() {
+			continue
+		}
+		victim := pickOOMVictim(hm.procQ)
+		hm.removeProcFromQ(victim)
+		hm.ctx.oomKilled(victim)
+	}
+}
+
+// how many procs there are per unit of compute the machine can do in a tick
+func (hm *HermodMachine) load() float64 {
+	return float64(len(hm.procQ)) / (float64(hm.numCores) * hm.speed)
+}
+
+// whether p would make its deadline if the machine ran EDF, going off of the guessed work of p and
+// of everything already on the machine with an earlier deadline
+func (hm *HermodMachine) deadlineFeasible(p *Proc) bool {
+	workAhead := p.remainingGuess()
+	for _, other := range hm.procQ {
+		if other.deadlineGuess() <= p.deadlineGuess() {
+			workAhead += other.remainingGuess()
+		}
+	}
+	finish := *hm.currTickPtr + workAhead/Tftick(float64(hm.numCores)*hm.speed)
+	return finish <= p.deadlineGuess()
+}
+
+// what the machine tells whoever probes it about placing p there
+type HermodProbe struct {
+	m        *HermodMachine
+	memFree  Tmem // the machine's warm instances can all make way for p
+	load     float64
+	nProcs   int
+	warm     bool
+	feasible bool // whether p would make its deadline, if the machine runs EDF
+}
+
+func (hm *HermodMachine) probeAnswer(p *Proc) HermodProbe {
+	return HermodProbe{
+		m:        hm,
+		memFree:  hm.memFree() + hm.warm.mem(),
+		load:     hm.load(),
+		nProcs:   len(hm.procQ),
+		warm:     hm.warm.isWarm(p),
+		feasible: hm.policy != LOCAL_EDF || hm.deadlineFeasible(p),
+	}
 }
 
 func (hm *HermodMachine) placeProc(newProc *Proc) {
 
 	newProc.timePlaced = *hm.currTickPtr
+	hm.ctx.procPlaced(newProc)
+	hm.warm.makeRoom(newProc, hm.memFree())
+	hm.ctx.startInstance(newProc, hm.warm)
+	hm.ctx.wake(hm.power, newProc)
 	hm.procQ = append(hm.procQ, newProc)
 
 }
 
 func (hm *HermodMachine) tick() {
 
-	// do PS across all the procs
+	// do PS across all the procs, or run them in the policy's order
 	ticksLeftPerCore := make(map[int]Tftick, hm.numCores)
 	totalTicksLeftToGive := Tftick(hm.numCores)
 
@@ -53,57 +120,96 @@ func (hm *HermodMachine) tick() {
 		ticksLeftPerCore[i] = Tftick(1)
 	}
 
-	// water-filling: assign procs to cores
-	procsPerCore := make(map[int][]*Proc)
-	currCore := 0
-	for _, p := range hm.procQ {
-		procsPerCore[currCore] = append(procsPerCore[currCore], p)
-		currCore += 1
-		if currCore == hm.numCores {
-			currCore = 0
-		}
-	}
-
 	ogMemFree := hm.memFree()
-	toWrite := fmt.Sprintf("%v, %v, %v", hm.worldNumProcsGenPerTick, int(*hm.currTickPtr), hm.machineId)
-	logWrite(HERMOD_USAGE, toWrite)
+	toWrite := fmt.Sprintf("%v%v, %v, %v", hm.ctx.logs.prefix, hm.worldNumProcsGenPerTick, int(*hm.currTickPtr), hm.machineId)
+	logWrite(hm.ctx.logs.usage, toWrite)
+
+	toWrite = fmt.Sprintf("\n%v==> %v @ %v, machine %v, mem free: %v, has q: \n%v", hm.ctx.logs.prefix, hm.worldNumProcsGenPerTick, hm.currTickPtr.String(), hm.machineId, hm.memFree(), hm.procQ)
+	logWrite(hm.ctx.logs.sched, toWrite)
 
-	toWrite = fmt.Sprintf("\n==> %v @ %v, machine %v, mem free: %v, has q: \n%v", hm.worldNumProcsGenPerTick, hm.currTickPtr.String(), hm.machineId, hm.memFree(), hm.procQ)
-	logWrite(HERMOD_SCHED, toWrite)
+	runOnCore := func(currProc *Proc, currCore int, ticksToGive Tftick) {
 
-	// water-filling, assign ticks to procs
-	for currCore := 0; currCore < hm.numCores; currCore++ {
+		overhead := hm.ctx.switchOverhead(currProc, hm.machineId, currCore, hm.coreHist, ticksToGive)
+		ticksUsed, done := currProc.runAtSpeed(ticksToGive-overhead, hm.speed)
 
-		for len(procsPerCore[currCore]) > 0 && ticksLeftPerCore[currCore]-Tftick(TICK_SCHED_THRESHOLD) > 0.0 {
+		ticksLeftPerCore[currCore] -= ticksUsed + overhead
+		totalTicksLeftToGive -= ticksUsed + overhead
 
-			ticksToGive := ticksLeftPerCore[currCore] / Tftick(len(procsPerCore[currCore]))
-			currProc := procsPerCore[currCore][0]
-			procsPerCore[currCore] = procsPerCore[currCore][1:]
+		if !done {
+			currProc.blockIfAtIO(*hm.currTickPtr + (1 - ticksLeftPerCore[currCore]))
+		} else {
+			currProc.timeDone = *hm.currTickPtr + (1 - ticksLeftPerCore[currCore])
+			hm.ctx.procDone(currProc)
+			hm.warm.release(currProc)
 
-			ticksUsed, done := currProc.runTillOutOrDone(ticksToGive)
+			toWrite = fmt.Sprintf("%v%v, %v, %v, %v \n", hm.ctx.logs.prefix, hm.worldNumProcsGenPerTick, currProc.willingToSpend(), (currProc.timeDone - currProc.timeStarted).String(), currProc.compDone.String())
+			logWrite(hm.ctx.logs.procsDone, toWrite)
 
-			ticksLeftPerCore[currCore] -= ticksUsed
-			totalTicksLeftToGive -= ticksUsed
+			hm.removeProcFromQ(currProc)
+		}
+	}
 
-			if done {
+	if hm.policy == LOCAL_PS {
+
+		// water-filling: assign procs to cores, and then share each core among its procs
+		procsPerCore := make(map[int][]*Proc)
+		currCore := 0
+		for _, p := range hm.policy.order(hm.procQ, *hm.currTickPtr) {
+			procsPerCore[currCore] = append(procsPerCore[currCore], p)
+			currCore += 1
+			if currCore == hm.numCores {
+				currCore = 0
+			}
+		}
 
-				currProc.timeDone = *hm.currTickPtr + (1 - ticksLeftPerCore[currCore])
+		for currCore := 0; currCore < hm.numCores; currCore++ {
+			for len(procsPerCore[currCore]) > 0 && ticksLeftPerCore[currCore]-Tftick(TICK_SCHED_THRESHOLD) > 0.0 {
+				ticksToGive := ticksLeftPerCore[currCore] / Tftick(len(procsPerCore[currCore]))
+				currProc := procsPerCore[currCore][0]
+				procsPerCore[currCore] = procsPerCore[currCore][1:]
+				runOnCore(currProc, currCore, ticksToGive)
+			}
+		}
 
-				toWrite = fmt.Sprintf("%v, %v, %v, %v \n", hm.worldNumProcsGenPerTick, currProc.willingToSpend(), (currProc.timeDone - currProc.timeStarted).String(), currProc.compDone.String())
-				logWrite(HERMOD_PROCS_DONE, toWrite)
+	} else {
 
-				hm.removeProcFromQ(currProc)
+		// one order for the whole machine: each proc in turn gets whichever core has the most time
+		// left, so no core idles while there is something it could be running
+		for _, currProc := range hm.policy.order(hm.procQ, *hm.currTickPtr) {
+			currCore := -1
+			for i := 0; i < hm.numCores; i++ {
+				if ticksLeftPerCore[i]-Tftick(TICK_SCHED_THRESHOLD) > 0.0 && (currCore == -1 || ticksLeftPerCore[i] > ticksLeftPerCore[currCore]) {
+					currCore = i
+				}
+			}
+			if currCore == -1 {
+				break
 			}
+			runOnCore(currProc, currCore, ticksLeftPerCore[currCore])
 		}
 	}
 
+	hm.checkOOM()
+
 	if totalTicksLeftToGive < 0.00002 {
 		totalTicksLeftToGive = 0
 	}
 
 	toWrite = fmt.Sprintf(", %.3f, %v\n", float64(math.Copysign(float64(totalTicksLeftToGive), 1)), ogMemFree)
-	logWrite(HERMOD_USAGE, toWrite)
+	logWrite(hm.ctx.logs.usage, toWrite)
+
+	hm.lastTicksIdle = totalTicksLeftToGive
+	hm.ctx.machineTicked(hm.power, hm.numCores, totalTicksLeftToGive, physMemUsed(hm.procQ)+hm.warm.mem())
+
+}
 
+// the machine goes down, and all of the procs on it are lost, as are its warm instances
+func (hm *HermodMachine) fail() []*Proc {
+	lost := hm.procQ
+	hm.procQ = make([]*Proc, 0)
+	hm.coreHist = CoreHistory{}
+	hm.warm = newWarmCache(hm.totalMem)
+	return lost
 }
 
 func (hm *HermodMachine) removeProcFromQ(procToRemove *Proc) {
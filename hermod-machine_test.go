@@ -0,0 +1,40 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// with a policy other than PS, a core that is done with what it was running moves on to whatever is
+// next in the machine's order, rather than idling while another core has a backlog
+func TestHermodMachineOrderedFillsCores(t *testing.T) {
+	currTick := Tftick(1)
+	ctx := newLBCtx("test", 0, &currTick, 2)
+	hm := newHermodMachine(0, 2, MEM_PER_MACHINE, 1, &currTick, 0, ctx, LOCAL_SRPT)
+
+	// guessed to be short but actually long, short, and long
+	for i, comp := range [][2]float32{{5, 0.1}, {0.2, 0.2}, {5, 1}} {
+		p := newProvProc(Tid(i), 0, newPrivProc(comp[0], comp[1], 1, 100))
+		hm.placeProc(p)
+	}
+	hm.tick()
+
+	if hm.lastTicksIdle > 0.001 {
+		t.Fatalf("cores were idle for %v while procs were waiting", hm.lastTicksIdle)
+	}
+	if len(hm.procQ) != 2 {
+		t.Fatalf("%v procs left, want the two long ones", len(hm.procQ))
+	}
+}
+
+// nothing stays warm across a crash
+func TestHermodMachineFailFlushesWarm(t *testing.T) {
+	currTick := Tftick(1)
+	ctx := newLBCtx("test", 0, &currTick, 2)
+	hm := newHermodMachine(0, 2, MEM_PER_MACHINE, 1, &currTick, 0, ctx, LOCAL_PS)
+	hm.warm.instances = []int{1, 2}
+
+	hm.fail()
+	if len(hm.warm.instances) != 0 {
+		t.Fatalf("%v warm instances survived the machine failing", len(hm.warm.instances))
+	}
+}
@@ -2,21 +2,30 @@ package slasched
 
 type IdealLB struct {
 	currTickPtr *Tftick
+	ctx         *LBCtx
 
 	multiQ     MultiQueue
 	bigMachine *BigIdealMachine
 }
 
-func newIdealLB(numMachines int, numCores int, nGenPerTick int, currrTickPtr *Tftick) *IdealLB {
+// also does centralized SRPT, with the big machine running procs in a different order
+func newIdealLB(fleet FleetSpec, nGenPerTick int, currrTickPtr *Tftick, ctx *LBCtx, policy LocalPolicy) *IdealLB {
 	ilb := &IdealLB{
 		currTickPtr: currrTickPtr,
+		ctx:         ctx,
 		multiQ:      NewMultiQ(),
-		bigMachine:  newBigIdealMachine(numMachines*numCores, Tmem(numMachines*MEM_PER_MACHINE), currrTickPtr, nGenPerTick),
+		bigMachine:  newBigIdealMachine(fleet.coreSpeeds(), fleet.totalMem(), currrTickPtr, nGenPerTick, ctx, policy),
 	}
 
+	ctx.enqProc = ilb.enqProc
+
 	return ilb
 }
 
+func (ilb *IdealLB) getCtx() *LBCtx {
+	return ilb.ctx
+}
+
 func (ilb *IdealLB) enqProc(proc *Proc) {
 
 	ilb.multiQ.enq(proc)
@@ -32,9 +41,7 @@ func (ilb *IdealLB) placeProcs() {
 	for p != nil {
 		placed, killed := ilb.bigMachine.potPlaceProc(p)
 
-		if killed != nil {
-			toReq = append(toReq, killed)
-		}
+		toReq = append(toReq, killed...)
 
 		if !placed {
 			toReq = append(toReq, p)
@@ -13,17 +13,27 @@ type BigIdealMachine struct {
 	currTickPtr             *Tftick
 	procQ                   *Queue
 	amtWorkPerTick          int
+	coreSpeeds              []float64
 	totalMem                Tmem
 	worldNumProcsGenPerTick int
+	ctx                     *LBCtx
+	coreHist                CoreHistory
+	warm                    *WarmCache
+	power                   *PowerState
 }
 
-func newBigIdealMachine(amtWorkPerTick int, totMem Tmem, currTickPtr *Tftick, worldNumProcsGenPerTick int) *BigIdealMachine {
+func newBigIdealMachine(coreSpeeds []float64, totMem Tmem, currTickPtr *Tftick, worldNumProcsGenPerTick int, ctx *LBCtx, policy LocalPolicy) *BigIdealMachine {
 	return &BigIdealMachine{
 		currTickPtr:             currTickPtr,
-		procQ:                   newQueue(),
-		amtWorkPerTick:          amtWorkPerTick,
+		procQ:                   newOrderedQueue(policy.less()),
+		amtWorkPerTick:          len(coreSpeeds),
+		coreSpeeds:              coreSpeeds,
 		totalMem:                totMem,
 		worldNumProcsGenPerTick: worldNumProcsGenPerTick,
+		ctx:                     ctx,
+		coreHist:                CoreHistory{},
+		warm:                    newWarmCache(totMem),
+		power:                   newPowerState(),
 	}
 
 }
@@ -32,28 +42,57 @@ func (idc *BigIdealMachine) memFree() Tmem {
 	currMemUsed := Tmem(0)
 
 	for _, p := range idc.procQ.getQ() {
-		currMemUsed += p.maxMem()
+		currMemUsed += p.memCharged()
 	}
 
-	return idc.totalMem - currMemUsed
+	return idc.totalMem - currMemUsed - idc.warm.mem()
 }
 
-func (idc *BigIdealMachine) potPlaceProc(newProc *Proc) (bool, *Proc) {
+func (idc *BigIdealMachine) checkOOM() {
+	for physMemUsed(idc.procQ.getQ())+idc.warm.mem() > idc.totalMem {
+		if idc.warm.drop() {
+			continue
+		}
+		victim := pickOOMVictim(idc.procQ.getQ())
+		idc.procQ.kill(victim.procId)
+		idc.ctx.oomKilled(victim)
+	}
+}
+
+func (idc *BigIdealMachine) potPlaceProc(newProc *Proc) (bool, []*Proc) {
 
-	// if it just fits in terms of memory do it
-	if newProc.maxMem() < idc.memFree() {
+	// if it just fits in terms of memory do it; the warm instances can all make way for it, but only
+	// get dropped once it is actually placed
+	memFree := idc.memFree() + idc.warm.mem()
+	if newProc.maxMem() < memFree {
 
 		newProc.timePlaced = *idc.currTickPtr
+		idc.warm.makeRoom(newProc, idc.memFree())
+		idc.ctx.startInstance(newProc, idc.warm)
+		idc.ctx.restore(newProc)
+		idc.ctx.wake(idc.power, newProc)
 		idc.procQ.enq(newProc)
 		return true, nil
 	}
 
-	// if it doesn't fit, look if there a good proc to kill? (/a combination of procs? can add that later)
-	procToKill, timeToProfit := idc.procQ.checkKill(newProc)
+	// if it doesn't fit, look if there are good procs to kill
+	victims, timeToProfit := idc.procQ.checkKill(newProc, memFree)
 	if timeToProfit < TIME_TO_PROFIT_THRESHOLD {
 
+		killed := make([]*Proc, 0, len(victims))
+		for _, p := range victims {
+			if idc.ctx.evict(p) {
+				idc.procQ.kill(p.procId)
+				killed = append(killed, p)
+			}
+		}
+		idc.ctx.evicted(victims)
+
 		newProc.timePlaced = *idc.currTickPtr
-		killed := idc.procQ.kill(procToKill)
+		idc.warm.makeRoom(newProc, idc.memFree())
+		idc.ctx.startInstance(newProc, idc.warm)
+		idc.ctx.restore(newProc)
+		idc.ctx.wake(idc.power, newProc)
 		idc.procQ.enq(newProc)
 		return true, killed
 	}
@@ -65,8 +104,10 @@ func (idc *BigIdealMachine) potPlaceProc(newProc *Proc) (bool, *Proc) {
 // ok so I have a bunch of procs that all fit memory wise, so really what I'm doing
 func (idc *BigIdealMachine) tick() {
 
-	toWrite := fmt.Sprintf("%v @ %v; mem free: %v: WHOLE QUEUE %v\n", idc.worldNumProcsGenPerTick, idc.currTickPtr, idc.memFree(), idc.procQ.String())
-	logWrite(IDEAL_SCHED, toWrite)
+	idc.ctx.swapIn(idc.procQ.getQ(), idc.memFree())
+
+	toWrite := fmt.Sprintf("%v%v @ %v; mem free: %v: WHOLE QUEUE %v\n", idc.ctx.logs.prefix, idc.worldNumProcsGenPerTick, idc.currTickPtr, idc.memFree(), idc.procQ.String())
+	logWrite(idc.ctx.logs.sched, toWrite)
 
 	totalTicksLeftToGive := Tftick(idc.amtWorkPerTick)
 	ticksLeftPerCore := make(map[int]Tftick, 0)
@@ -79,8 +120,8 @@ func (idc *BigIdealMachine) tick() {
 	}
 
 	ogMemFree := idc.memFree()
-	toWrite = fmt.Sprintf("%v, %v", idc.worldNumProcsGenPerTick, int(*idc.currTickPtr))
-	logWrite(IDEAL_USAGE, toWrite)
+	toWrite = fmt.Sprintf("%v%v, %v", idc.ctx.logs.prefix, idc.worldNumProcsGenPerTick, int(*idc.currTickPtr))
+	logWrite(idc.ctx.logs.usage, toWrite)
 
 	// TODO: what if it doesn't fit?
 	putProcOnCoreWithMaxTimeLeft := func() int {
@@ -102,6 +143,8 @@ func (idc *BigIdealMachine) tick() {
 
 	toReq := make([]*Proc, 0)
 
+	blocked := idc.procQ.removeBlocked(*idc.currTickPtr)
+
 	for idc.procQ.qlen() > 0 && totalTicksLeftToGive-Tftick(TICK_SCHED_THRESHOLD) > 0.0 && len(coresWithTicksLeft) > 0 {
 
 		for i := 0; i < idc.amtWorkPerTick; i++ {
@@ -134,33 +177,37 @@ func (idc *BigIdealMachine) tick() {
 			}
 
 			toWrite := fmt.Sprintf("   core %v giving %v to proc %v \n", currCore, ticksLeftPerCore[currCore], procToRun.String())
-			logWrite(IDEAL_SCHED, toWrite)
+			logWrite(idc.ctx.logs.sched, toWrite)
 
-			ticksUsed, done := procToRun.runTillOutOrDone(ticksLeftPerCore[currCore])
+			overhead := idc.ctx.switchOverhead(procToRun, 0, currCore, idc.coreHist, ticksLeftPerCore[currCore])
+			ticksUsed, done := procToRun.runAtSpeed(ticksLeftPerCore[currCore]-overhead, idc.coreSpeeds[currCore])
 
-			ticksLeftPerCore[currCore] -= ticksUsed
-			totalTicksLeftToGive -= ticksUsed
+			ticksLeftPerCore[currCore] -= ticksUsed + overhead
+			totalTicksLeftToGive -= ticksUsed + overhead
 
 			if ticksLeftPerCore[currCore] < Tftick(TICK_SCHED_THRESHOLD) {
 				delete(coresWithTicksLeft, currCore)
 			}
 
 			if !done {
+				procToRun.blockIfAtIO(*idc.currTickPtr + (1 - ticksLeftPerCore[currCore]))
 				toReq = append(toReq, procToRun)
 			} else {
 				// if the proc is done, update the ticksPassed to be exact for metrics etc
 				procToRun.timeDone = *idc.currTickPtr + (1 - ticksLeftPerCore[currCore])
+				idc.ctx.procDone(procToRun)
+				idc.warm.release(procToRun)
 
 				toWrite := fmt.Sprintf("   -> done: %v\n", procToRun.String())
-				logWrite(IDEAL_SCHED, toWrite)
+				logWrite(idc.ctx.logs.sched, toWrite)
 
-				if (procToRun.timeDone - procToRun.timeStarted) > procToRun.compDone {
+				if procToRun.timeDone > procToRun.deadline() {
 					toWrite := fmt.Sprintf("   ---> OVER %v \n", procToRun.String())
-					logWrite(IDEAL_SCHED, toWrite)
+					logWrite(idc.ctx.logs.sched, toWrite)
 				}
 
-				toWrite = fmt.Sprintf("%v, %v, %v, %v \n", idc.worldNumProcsGenPerTick, procToRun.willingToSpend(), (procToRun.timeDone - procToRun.timeStarted).String(), procToRun.compDone.String())
-				logWrite(IDEAL_PROCS_DONE, toWrite)
+				toWrite = fmt.Sprintf("%v%v, %v, %v, %v \n", idc.ctx.logs.prefix, idc.worldNumProcsGenPerTick, procToRun.willingToSpend(), (procToRun.timeDone - procToRun.timeStarted).String(), procToRun.compDone.String())
+				logWrite(idc.ctx.logs.procsDone, toWrite)
 			}
 
 		}
@@ -170,13 +217,20 @@ func (idc *BigIdealMachine) tick() {
 	for _, p := range toReq {
 		idc.procQ.enq(p)
 	}
+	for _, p := range blocked {
+		idc.procQ.enq(p)
+	}
+
+	idc.checkOOM()
 
 	toWrite = fmt.Sprintf("cores with ticks left: %v, ticks left over: %v\n", coresWithTicksLeft, ticksLeftPerCore)
-	logWrite(IDEAL_SCHED, toWrite)
+	logWrite(idc.ctx.logs.sched, toWrite)
 
 	if totalTicksLeftToGive < 0.00002 {
 		totalTicksLeftToGive = 0
 	}
 	toWrite = fmt.Sprintf(", %.3f, %v\n", float64(math.Copysign(float64(totalTicksLeftToGive), 1)), ogMemFree)
-	logWrite(IDEAL_USAGE, toWrite)
+	logWrite(idc.ctx.logs.usage, toWrite)
+
+	idc.ctx.machineTicked(idc.power, idc.amtWorkPerTick, totalTicksLeftToGive, physMemUsed(idc.procQ.getQ())+idc.warm.mem())
 }
@@ -0,0 +1,28 @@
+package slasched
+
+import (
+	"sort"
+)
+
+// after atComp ticks of compute, the proc blocks (eg on a call to a storage service) for duration
+// ticks, during which it holds on to its memory but not to a core
+type IOPhase struct {
+	atComp   Tftick
+	duration Tftick
+}
+
+func genIOPhases(actualComp float64) []IOPhase {
+
+	nPhases := 1 + r.Intn(MAX_IO_PHASES)
+
+	phases := make([]IOPhase, nPhases)
+	for i := 0; i < nPhases; i++ {
+		phases[i] = IOPhase{
+			atComp:   Tftick(r.Float64() * actualComp),
+			duration: Tftick(r.ExpFloat64() * AVG_IO_TIME),
+		}
+	}
+	sort.Slice(phases, func(i, j int) bool { return phases[i].atComp < phases[j].atComp })
+
+	return phases
+}
@@ -0,0 +1,41 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// a proc that gets to an I/O phase gives up its core until the I/O is done, then picks up where it
+// left off
+func TestIOBlocksAndWakes(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 1)
+	hm := newHermodMachine(0, 1, MEM_PER_MACHINE, 1, &currTick, 0, ctx, LOCAL_PS)
+
+	pi := newPrivProc(3, 3, 1, 100)
+	pi.ioPhases = []IOPhase{{atComp: 1, duration: 2}}
+	p := newProvProc(0, 0, pi)
+	hm.placeProc(p)
+
+	// runs up to the I/O in the first tick, and blocks until 1+2
+	hm.tick()
+	if p.compDone != 1 || p.blockedUntil != 3 {
+		t.Fatalf("after the first tick the proc did %v and is blocked until %v, want 1 and 3", p.compDone, p.blockedUntil)
+	}
+
+	for currTick = 1; currTick < 3; currTick++ {
+		hm.tick()
+		if p.compDone != 1 {
+			t.Fatalf("proc ran while blocked on I/O at %v", currTick)
+		}
+		if hm.lastTicksIdle != 1 {
+			t.Fatalf("core was idle for %v while the only proc was doing I/O, want 1", hm.lastTicksIdle)
+		}
+	}
+
+	for ; currTick < 5; currTick++ {
+		hm.tick()
+	}
+	if len(hm.procQ) != 0 || p.timeDone != 5 {
+		t.Fatalf("proc done at %v (still queued: %v), want done at 5", p.timeDone, len(hm.procQ) != 0)
+	}
+}
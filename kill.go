@@ -0,0 +1,81 @@
+package slasched
+
+import "sort"
+
+// what happens to a proc that gets evicted to make room for a pricier one
+type KillSemantics int
+
+const (
+	KILL_RESTART    KillSemantics = iota // it starts over from scratch somewhere else
+	KILL_CHECKPOINT                      // it saves its progress, and picks up from there somewhere else
+	KILL_SUSPEND                         // it gets swapped out, and stays on the machine until there is room to swap it back in
+)
+
+// called for every proc a machine evicts; returns whether the proc leaves the machine (and so has to
+// go back to the LB) or stays on it suspended
+func (ctx *LBCtx) evict(p *Proc) bool {
+	now := *ctx.currTickPtr
+
+	switch ctx.killSemantics {
+	case KILL_RESTART:
+		ctx.stats.inc("evict_wasted_work", float64(p.compDone))
+		p.restart()
+		return true
+
+	case KILL_SUSPEND:
+		// the proc can't run while it is being swapped out
+		cost := SWAP_COST_PER_MEM * float64(p.memUsed())
+		if cost > 0 {
+			ctx.stats.inc("evict_swap_time", cost)
+		}
+		p.blockedUntil = max(p.blockedUntil, now+Tftick(cost))
+		p.swapped = true
+		return false
+
+	default:
+		// the proc can't go anywhere until it is saved, and once it is placed it has to be restored
+		if cost := CHECKPOINT_SAVE_COST + CHECKPOINT_RESTORE_COST; cost > 0 {
+			ctx.stats.inc("evict_checkpoint_time", cost)
+		}
+		p.blockedUntil = max(p.blockedUntil, now+CHECKPOINT_SAVE_COST)
+		p.restoreCost = CHECKPOINT_RESTORE_COST
+		return true
+	}
+}
+
+// called by the machines that evict when they place a proc, in case it was checkpointed
+func (ctx *LBCtx) restore(p *Proc) {
+	if p.restoreCost == 0 {
+		return
+	}
+	p.blockedUntil = max(p.blockedUntil, *ctx.currTickPtr+p.restoreCost)
+	p.restoreCost = 0
+}
+
+// swap suspended procs back in, priciest first, for as long as they fit. Swapping in only starts
+// once swapping out is done, and the proc can't run until both are
+func (ctx *LBCtx) swapIn(procs []*Proc, memFree Tmem) {
+
+	swapped := make([]*Proc, 0)
+	for _, p := range procs {
+		if p.swapped {
+			swapped = append(swapped, p)
+		}
+	}
+	sort.SliceStable(swapped, func(i, j int) bool { return swapped[i].willingToSpend() > swapped[j].willingToSpend() })
+
+	for _, p := range swapped {
+		if p.maxMem() >= memFree {
+			continue
+		}
+		p.swapped = false
+		memFree -= p.memCharged()
+
+		cost := SWAP_COST_PER_MEM * float64(p.memUsed())
+		if cost > 0 {
+			ctx.stats.inc("evict_swap_time", cost)
+		}
+		p.blockedUntil = max(p.blockedUntil, *ctx.currTickPtr) + Tftick(cost)
+		ctx.stats.inc("swapped_in", 1)
+	}
+}
@@ -0,0 +1,63 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// a suspended proc gives up its memory but stays put, and once there is room again it is swapped
+// back in; it can't run while either swap is going on
+func TestSuspendSwapsOutAndIn(t *testing.T) {
+	currTick := Tftick(1)
+	ctx := newLBCtx("test", 0, &currTick, 1)
+	ctx.killSemantics = KILL_SUSPEND
+
+	p := newTestProc(0, 10, 1, 1000)
+	p.compDone = 2
+	swapCost := Tftick(SWAP_COST_PER_MEM * 1000)
+
+	if ctx.evict(p) {
+		t.Fatalf("suspended proc left the machine")
+	}
+	if !p.swapped || p.memCharged() != 0 {
+		t.Fatalf("suspended proc still holds %v memory", p.memCharged())
+	}
+	if p.blockedUntil != currTick+swapCost {
+		t.Fatalf("blocked until %v while swapping out, want %v", p.blockedUntil, currTick+swapCost)
+	}
+	if p.compDone != 2 {
+		t.Fatalf("suspended proc lost its progress")
+	}
+
+	// not enough room to swap it back in
+	ctx.swapIn([]*Proc{p}, 500)
+	if !p.swapped {
+		t.Fatalf("swapped in without room for it")
+	}
+
+	ctx.swapIn([]*Proc{p}, 2000)
+	if p.swapped || p.memCharged() != 1000 {
+		t.Fatalf("not swapped back in with room to spare")
+	}
+	if diff := p.blockedUntil - (currTick + 2*swapCost); diff > 0.0001 || diff < -0.0001 {
+		t.Fatalf("blocked until %v after swapping back in, want %v", p.blockedUntil, currTick+2*swapCost)
+	}
+	if p.runnable(currTick + swapCost) {
+		t.Fatalf("runnable before swapping in is done")
+	}
+	if ctx.stats.get("swapped_in") != 1 {
+		t.Fatalf("swap in not counted")
+	}
+}
+
+// a proc that starts over (eg because its machine failed) has nothing to swap in or restore anymore
+func TestRestartClearsEvictionState(t *testing.T) {
+	p := newTestProc(0, 10, 1, 1000)
+	p.compDone = 2
+	p.swapped = true
+	p.restoreCost = 0.5
+
+	p.restart()
+	if p.swapped || p.restoreCost != 0 || p.compDone != 0 {
+		t.Fatalf("restarted proc still swapped: %v, restore cost: %v, comp done: %v", p.swapped, p.restoreCost, p.compDone)
+	}
+}
@@ -0,0 +1,101 @@
+package slasched
+
+// state that an LB shares with its machines and that isn't specific to any one scheduling policy
+type LBCtx struct {
+	lbName      string
+	nGenPerTick int
+	currTickPtr *Tftick
+	predictor   RuntimePredictor
+	stats       *LBStats
+	dags        *DAGTracker
+	failures    *FailureModel
+	autoscaler  *Autoscaler
+	net         *Network
+	latencies   []float64 // of every proc that finished, for the tail
+	logs        LBLogs
+	enqProc     func(*Proc) // puts a proc (back) into the LB's queues, for procs that don't come from the world
+
+	// for admission control: how much compute the LB has per tick, and how much guessed work it has
+	// taken in that isn't done yet, by price
+	admission AdmissionController
+	capacity  float64
+	backlog   map[float32]Tftick
+
+	market        *Market
+	killSemantics KillSemantics
+	switchCosts   SwitchCosts
+	powerOffAfter int
+}
+
+func newLBCtx(lbName string, nGenPerTick int, currTickPtr *Tftick, capacity float64) *LBCtx {
+	ctx := &LBCtx{
+		lbName:      lbName,
+		nGenPerTick: nGenPerTick,
+		currTickPtr: currTickPtr,
+		predictor:   newRuntimePredictor(RUNTIME_PREDICTOR),
+		stats:       newLBStats(),
+		dags:        newDAGTracker(),
+		failures:    newFailureModel(),
+		autoscaler:  newAutoscaler(),
+		logs:        lbLogs(lbName),
+		admission:   newAdmissionController(ADMISSION_POLICY),
+		capacity:    capacity,
+		backlog:     map[float32]Tftick{},
+
+		killSemantics: KILL_SEMANTICS,
+		switchCosts:   SwitchCosts{CTX_SWITCH_COST, PREEMPT_COST, MIGRATION_COST},
+		powerOffAfter: POWER_OFF_AFTER,
+	}
+	ctx.net = newNetwork(ctx)
+	ctx.market = newMarket(ctx)
+	return ctx
+}
+
+// called once for every proc that enters the LB, before it is enqueued
+func (ctx *LBCtx) procArrived(p *Proc) {
+	p.compPred = ctx.predictor.predict(p)
+	p.instanceMem = instanceMem()
+	if p.procInternals.dag != nil {
+		ctx.dags.nodeArrived(ctx, p)
+	}
+	if MARKET_MODE {
+		ctx.market.join(p)
+	}
+	ctx.addBacklog(p, p.compGuess())
+}
+
+// called by the machines whenever a proc finishes
+func (ctx *LBCtx) procDone(p *Proc) {
+	ctx.predictor.observe(p)
+	ctx.addBacklog(p, -p.compGuess())
+	ctx.recordSLA(p)
+	if p.market != nil {
+		p.market.settle(p)
+	}
+	ctx.latencies = append(ctx.latencies, float64(p.timeDone-p.timeStarted))
+
+	if p.procInternals.dag != nil {
+		ctx.dags.nodeDone(ctx, p)
+	}
+}
+
+// called by a machine that ran out of physical memory and killed p; the proc starts over
+func (ctx *LBCtx) oomKilled(p *Proc) {
+	ctx.stats.inc("oom_kills", 1)
+	ctx.stats.inc("oom_lost_work", float64(p.compDone))
+
+	p.restart()
+	ctx.enqProc(p)
+}
+
+func (ctx *LBCtx) writeStats() {
+	ctx.stats.write(ctx.nGenPerTick, ctx.lbName)
+	ctx.writeSLARates()
+	ctx.writeRejectionRates()
+	ctx.writeColdStartRate()
+	ctx.writeDefragVsKill()
+	ctx.writeMsgRates()
+	ctx.writeTailAndUtil()
+	ctx.writeEnergy()
+	ctx.writeMarket()
+}
@@ -1,6 +1,9 @@
 package slasched
 
-import "math"
+import (
+	"math"
+	"math/rand"
+)
 
 // constants characterizing the wesbite traffic
 const (
@@ -15,6 +18,9 @@ const (
 	MAX_MEM = 10000
 
 	PARETO_ALPHA = 25
+
+	N_FUNCTIONS = 0   // how many functions the procs are instances of; 0 means procs don't have one
+	FUNC_ZIPF_S = 1.1 // how skewed function popularity is
 )
 
 type LoadGen interface {
@@ -23,10 +29,15 @@ type LoadGen interface {
 
 // the website struct itself
 type LoadGenT struct {
+	funcPopularity *rand.Zipf
 }
 
 func newLoadGen() *LoadGenT {
-	return &LoadGenT{}
+	lg := &LoadGenT{}
+	if nFuncs := N_FUNCTIONS; nFuncs > 0 {
+		lg.funcPopularity = rand.NewZipf(r, FUNC_ZIPF_S, 1, uint64(nFuncs-1))
+	}
+	return lg
 }
 
 func (lg *LoadGenT) genLoad(nProcs int) []*ProcInternals {
@@ -34,18 +45,53 @@ func (lg *LoadGenT) genLoad(nProcs int) []*ProcInternals {
 
 	for i := 0; i < nProcs; i++ {
 
-		minComp := math.Max(math.Min(sampleNormal(AVG_COMP, STD_DEV_COMP), MAX_COMP), MIN_COMP)
-		actualComp := ParetoSample(PARETO_ALPHA, float64(minComp))
-
-		expectedValOfPareto := (PARETO_ALPHA * minComp) / (PARETO_ALPHA - 1)
-
 		priority := genRandPriority()
-		willingToSpend := mapPriorityToDollars(priority)
 
-		maxMem := MIN_MEM + r.Intn(MAX_MEM-MIN_MEM)
+		procs[i] = lg.genProc(priority)
 
-		procs[i] = newPrivProc(float32(actualComp), float32(expectedValOfPareto), willingToSpend, maxMem)
+		// only the root goes out now, the rest of the DAG gets released as its parents finish
+		if DAG_PROB > 0 && r.Float64() < DAG_PROB {
+			genDAG(procs[i], func() *ProcInternals { return lg.genProc(priority) })
+		}
 	}
 
 	return procs
 }
+
+func (lg *LoadGenT) genProc(priority int) *ProcInternals {
+
+	minComp := math.Max(math.Min(sampleNormal(AVG_COMP, STD_DEV_COMP), MAX_COMP), MIN_COMP)
+	actualComp := ParetoSample(PARETO_ALPHA, float64(minComp))
+
+	expectedValOfPareto := (PARETO_ALPHA * minComp) / (PARETO_ALPHA - 1)
+
+	maxMem := MIN_MEM + r.Intn(MAX_MEM-MIN_MEM)
+
+	proc := newPrivProc(float32(actualComp), float32(expectedValOfPareto), mapPriorityToDollars(priority), maxMem)
+
+	if SLA_TYPE == SLA_DEADLINE {
+		proc.slaDeadline = mapPriorityToDeadline(priority)
+	} else {
+		proc.slaSlowdown = mapPriorityToSlowdown(priority)
+	}
+
+	if MEM_TRAJECTORIES {
+		proc.memPhases = genMemPhases(actualComp, maxMem)
+	}
+
+	if IO_PROB > 0 && r.Float64() < IO_PROB {
+		proc.ioPhases = genIOPhases(actualComp)
+	}
+
+	if N_FUNCTIONS > 0 {
+		proc.funcId = int(lg.funcPopularity.Uint64())
+	}
+
+	// clients that pay more for their class mostly value their compute more, too
+	if MARKET_MODE {
+		proc.valuation = proc.willingToSpend * float32(math.Exp(sampleNormal(0, MARKET_VALUATION_SIGMA)))
+		proc.bid = proc.valuation * MARKET_BID_SHADE
+	}
+
+	return proc
+}
@@ -0,0 +1,110 @@
+package slasched
+
+import "fmt"
+
+// market mode: every client has a private valuation of its proc's compute and bids some of it. The
+// provider sets a spot price every tick from how busy it is; procs bidding less than the spot price
+// aren't admitted, and ones that are running get suspended (keeping their memory) until the price
+// comes back down. Procs pay the spot price for every unit of compute they get
+type Market struct {
+	ctx       *LBCtx
+	price     float64
+	procs     map[*Proc]bool // admitted and not done yet
+	lastCores float64        // the core_ticks and idle_core_ticks stats as of the last tick
+	lastIdle  float64
+	priceSum  float64
+	nTicks    int
+}
+
+func newMarket(ctx *LBCtx) *Market {
+	return &Market{
+		ctx:   ctx,
+		price: MARKET_START_PRICE,
+		procs: map[*Proc]bool{},
+	}
+}
+
+func (m *Market) join(p *Proc) {
+	p.market = m
+	m.procs[p] = true
+}
+
+func (m *Market) leave(p *Proc) {
+	delete(m.procs, p)
+}
+
+func (m *Market) outbid(p *Proc) bool {
+	return float64(p.bid()) < m.price
+}
+
+// a preemption is a proc that already got to run being priced out; procs that were outbid before
+// they ever ran (or at the door, see admit) don't count
+func (m *Market) countPreemptions() {
+	for p := range m.procs {
+		out := m.outbid(p)
+		if out && !p.outbid && p.compDone > 0 {
+			m.ctx.stats.inc("market_preemptions", 1)
+		}
+		p.outbid = out
+	}
+}
+
+func (m *Market) charge(p *Proc, work Tftick) {
+	cost := float64(work) * m.price
+	p.paid += cost
+	m.ctx.stats.inc("market_revenue", cost)
+}
+
+// the client only gets value out of the proc if it made its SLA
+func (m *Market) settle(p *Proc) {
+	value := 0.0
+	if p.timeDone <= p.deadline() {
+		value = float64(p.procInternals.valuation) * float64(p.procInternals.actualComp)
+	}
+	m.ctx.stats.inc("market_client_value", value)
+	m.ctx.stats.inc("market_client_surplus", value-p.paid)
+	m.leave(p)
+}
+
+// how long it would take to work off the guessed work left of the procs that can afford the price,
+// past the tick's worth that is running (see queueingDelay)
+func (m *Market) demandDelay() float64 {
+	work := Tftick(0)
+	for p := range m.procs {
+		if float64(p.bid()) >= m.price {
+			work += p.remainingGuess()
+		}
+	}
+	return max(float64(work)-m.ctx.capacity, 0) / m.ctx.capacity
+}
+
+// the price goes up when the LB is busier than MARKET_TARGET_UTIL or has a backlog of procs that
+// can pay, and down otherwise
+func (m *Market) tick() {
+
+	cores := m.ctx.stats.get("core_ticks")
+	idle := m.ctx.stats.get("idle_core_ticks")
+	util := 0.0
+	if cores > m.lastCores {
+		util = 1 - (idle-m.lastIdle)/(cores-m.lastCores)
+	}
+	m.lastCores, m.lastIdle = cores, idle
+
+	delay := m.demandDelay()
+	m.price = max(MARKET_MIN_PRICE, m.price*(1+MARKET_STEP*(util+delay-MARKET_TARGET_UTIL)))
+	m.priceSum += m.price
+	m.nTicks += 1
+
+	m.countPreemptions()
+
+	toWrite := fmt.Sprintf("%v, %v, %v, %.3f, %.3f, %.3f\n", m.ctx.nGenPerTick, m.ctx.lbName, int(*m.ctx.currTickPtr), m.price, util, delay)
+	logWrite(MARKET, toWrite)
+}
+
+func (ctx *LBCtx) writeMarket() {
+	if ctx.market.nTicks == 0 {
+		return
+	}
+	toWrite := fmt.Sprintf("%v, %v, %v, %.3f\n", ctx.nGenPerTick, ctx.lbName, "market_avg_price", ctx.market.priceSum/float64(ctx.market.nTicks))
+	logWrite(LB_STATS, toWrite)
+}
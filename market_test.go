@@ -0,0 +1,54 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// a bid below the spot price is outbid, and checking that doesn't count as preempting anything
+func TestMarketOutbid(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 1)
+	ctx.market.price = 1
+
+	low, high := newTestProc(0, 5, 1, 100), newTestProc(1, 5, 1, 100)
+	low.procInternals.bid, high.procInternals.bid = 0.5, 2
+	if !ctx.market.outbid(low) {
+		t.Fatalf("bid of %v isn't outbid at a price of %v", low.bid(), ctx.market.price)
+	}
+	if ctx.market.outbid(high) {
+		t.Fatalf("bid of %v is outbid at a price of %v", high.bid(), ctx.market.price)
+	}
+
+	ctx.market.join(low)
+	if low.runnable(currTick) {
+		t.Fatalf("outbid proc is runnable")
+	}
+	if preemptions := ctx.stats.get("market_preemptions"); preemptions != 0 {
+		t.Fatalf("%v preemptions without anything having run", preemptions)
+	}
+}
+
+// only a proc that already ran and then gets priced out counts as preempted
+func TestMarketPreemption(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 100)
+	ctx.market.price = 1
+
+	running, waiting := newTestProc(0, 5, 1, 100), newTestProc(1, 5, 1, 100)
+	running.procInternals.bid, waiting.procInternals.bid = 2, 2
+	ctx.market.join(running)
+	ctx.market.join(waiting)
+	running.compDone = 1
+
+	ctx.market.tick()
+	if preemptions := ctx.stats.get("market_preemptions"); preemptions != 0 {
+		t.Fatalf("%v preemptions while both procs are in the money", preemptions)
+	}
+
+	ctx.market.price = 10
+	ctx.market.tick()
+	ctx.market.tick()
+	if preemptions := ctx.stats.get("market_preemptions"); preemptions != 1 {
+		t.Fatalf("%v preemptions, want 1 for the proc that was running", preemptions)
+	}
+}
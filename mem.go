@@ -0,0 +1,169 @@
+package slasched
+
+import (
+	"math"
+	"sort"
+)
+
+type MemAccountingType int
+
+const (
+	MEM_RESERVED MemAccountingType = iota // machines count a proc's maxMem for its whole life
+	MEM_ACTUAL                            // machines count what procs actually use, so they can overcommit
+)
+
+type OOMVictimPolicy int
+
+const (
+	OOM_KILL_LARGEST  OOMVictimPolicy = iota // like the linux oom killer: whoever uses the most memory
+	OOM_KILL_CHEAPEST                        // lowest price first, biggest first among those
+	OOM_KILL_YOUNGEST                        // least compute done, ie least work thrown away
+)
+
+// a proc uses mem until it has done untilComp ticks of compute
+type MemPhase struct {
+	untilComp Tftick
+	mem       Tmem
+}
+
+// split the proc's compute into a few phases, each using some fraction of maxMem; one of the phases
+// is always the peak so that maxMem is a tight bound
+func genMemPhases(actualComp float64, maxMem int) []MemPhase {
+
+	nPhases := 1 + r.Intn(MAX_MEM_PHASES)
+	peakPhase := r.Intn(nPhases)
+
+	phases := make([]MemPhase, nPhases)
+	for i := 0; i < nPhases; i++ {
+		mem := maxMem
+		if i != peakPhase {
+			mem = int(float64(maxMem) * (MIN_MEM_PHASE_FRAC + r.Float64()*(1-MIN_MEM_PHASE_FRAC)))
+		}
+		phases[i] = MemPhase{
+			untilComp: Tftick(actualComp * float64(i+1) / float64(nPhases)),
+			mem:       Tmem(mem),
+		}
+	}
+
+	return phases
+}
+
+func physMemUsed(procs []*Proc) Tmem {
+	memUsed := Tmem(0)
+	for _, p := range procs {
+		memUsed += p.memUsed()
+	}
+	return memUsed
+}
+
+func pickOOMVictim(procs []*Proc) *Proc {
+
+	var victim *Proc
+
+	for _, p := range procs {
+		if victim == nil {
+			victim = p
+			continue
+		}
+		switch OOM_VICTIM_POLICY {
+		case OOM_KILL_CHEAPEST:
+			if p.willingToSpend() < victim.willingToSpend() ||
+				(p.willingToSpend() == victim.willingToSpend() && p.memUsed() > victim.memUsed()) {
+				victim = p
+			}
+		case OOM_KILL_YOUNGEST:
+			if p.compDone < victim.compDone {
+				victim = p
+			}
+		default:
+			if p.memUsed() > victim.memUsed() {
+				victim = p
+			}
+		}
+	}
+
+	return victim
+}
+
+// what killing p throws away
+func lostValue(p *Proc) float32 {
+	return float32(p.compDone) * p.willingToSpend()
+}
+
+// the procs to kill to make room for newProc: they all pay less than it does, together they free up
+// at least need memory, and they throw away as little as we could find. Greedy by lost value per
+// unit of memory, then drop the victims that turn out not to be needed; a single proc that frees
+// enough on its own wins if that's cheaper. Returns nil if no set of procs frees enough
+func pickVictims(procs []*Proc, newProc *Proc, need Tmem) ([]*Proc, float32) {
+
+	candidates := make([]*Proc, 0)
+	for _, p := range procs {
+		if p.willingToSpend() < newProc.willingToSpend() && p.memCharged() > 0 {
+			candidates = append(candidates, p)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return lostValue(candidates[i])/float32(candidates[i].memCharged()) < lostValue(candidates[j])/float32(candidates[j].memCharged())
+	})
+
+	victims := make([]*Proc, 0)
+	freed := Tmem(0)
+	for _, p := range candidates {
+		if freed >= need {
+			break
+		}
+		victims = append(victims, p)
+		freed += p.memCharged()
+	}
+	if freed < need {
+		return nil, float32(math.MaxFloat32)
+	}
+
+	// most expensive first, so those are the ones we get to keep if we can
+	sort.SliceStable(victims, func(i, j int) bool { return lostValue(victims[i]) > lostValue(victims[j]) })
+	needed := make([]*Proc, 0, len(victims))
+	for _, p := range victims {
+		if freed-p.memCharged() >= need {
+			freed -= p.memCharged()
+			continue
+		}
+		needed = append(needed, p)
+	}
+
+	lost := float32(0)
+	for _, p := range needed {
+		lost += lostValue(p)
+	}
+
+	for _, p := range candidates {
+		if p.memCharged() >= need && lostValue(p) < lost {
+			needed = []*Proc{p}
+			lost = lostValue(p)
+		}
+	}
+
+	return needed, lost
+}
+
+// how long it takes newProc to make up for the lost value of killing victims, going by how much
+// more it pays than the priciest of them; forever if there are no victims to kill
+func timeToProfit(newProc *Proc, victims []*Proc, lost float32) float32 {
+	if victims == nil {
+		return float32(math.MaxFloat32)
+	}
+
+	maxPrice := float32(0)
+	for _, p := range victims {
+		maxPrice = max(maxPrice, p.willingToSpend())
+	}
+
+	return lost / (newProc.willingToSpend() - maxPrice)
+}
+
+func (ctx *LBCtx) evicted(victims []*Proc) {
+	ctx.stats.inc("evictions", 1)
+	ctx.stats.inc("evicted_procs", float64(len(victims)))
+	if len(victims) > 1 {
+		ctx.stats.inc("multi_victim_evictions", 1)
+	}
+}
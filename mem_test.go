@@ -0,0 +1,35 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// two small procs that have barely run are cheaper to kill than one big one that has done a lot,
+// and procs that pay more than the new one are never picked
+func TestPickVictimsLeastLost(t *testing.T) {
+	big := newTestProc(0, 10, 1, 100)
+	small1 := newTestProc(1, 10, 1, 60)
+	small2 := newTestProc(2, 10, 1, 60)
+	pricey := newTestProc(3, 10, 3, 200)
+	newProc := newTestProc(4, 10, 2, 100)
+	big.compDone, small1.compDone, small2.compDone = 5, 1, 1
+
+	victims, lost := pickVictims([]*Proc{big, small1, small2, pricey}, newProc, 100)
+	if len(victims) != 2 || lost != 2 {
+		t.Fatalf("picked %v losing %v, want the two small procs losing 2", victims, lost)
+	}
+	for _, p := range victims {
+		if p != small1 && p != small2 {
+			t.Fatalf("picked %v, want the two small procs", p)
+		}
+	}
+
+	// the new proc pays 1 more than they did, so it takes 2 ticks to make up for them
+	if ttp := timeToProfit(newProc, victims, lost); ttp != 2 {
+		t.Fatalf("time to profit %v, want 2", ttp)
+	}
+
+	if victims, _ := pickVictims([]*Proc{big, small1, pricey}, newProc, 200); victims != nil {
+		t.Fatalf("picked %v even though the cheaper procs can't free enough", victims)
+	}
+}
@@ -11,6 +11,7 @@ type TIdleMachine struct {
 	highestCostRunning float32
 	qlen               int
 	memAvail           Tmem
+	compPerTick        float64
 }
 
 // TODO: basically we can think of this as a free list, should treat it accordingly (this is a well-known problem)
@@ -29,30 +30,41 @@ func (h *MinHeap) Pop() any {
 	return x
 }
 
-func useBestIdle(h *MinHeap, memNeeded Tmem) (TIdleMachine, bool) {
+func useBestIdle(h *MinHeap, memNeeded Tmem, isWarm func(Tid) bool) (TIdleMachine, bool) {
 
 	// under mem pressures: choose based off memory fitting
 
 	// if there are many where it would fit, pick based off qlen and highestCostRunning
 
 	possMachines := make([]TIdleMachine, 0)
+	warmMachines := make([]TIdleMachine, 0)
 
 	for ind := 0; ind < len(*h); ind++ {
 		idleMachine := (*h)[ind]
 		if idleMachine.memAvail > memNeeded {
 			possMachines = append(possMachines, idleMachine)
+			if isWarm(idleMachine.machine) {
+				warmMachines = append(warmMachines, idleMachine)
+			}
 		}
 	}
 
+	// if the proc's function is warm somewhere, only look there
+	if len(warmMachines) > 0 {
+		possMachines = warmMachines
+	}
+
 	// minHighestCost := float32(math.MaxFloat32)
-	minQlen := math.MaxInt
+	minQlen := math.MaxFloat64
 	indToUse := -1
 
 	for ind, idleMachine := range possMachines {
 		// trade qlen and priority off?
-		if idleMachine.qlen < minQlen {
+		// (qlen is relative to how much compute the machine has, since they don't all have the same)
+		qlen := float64(idleMachine.qlen) / idleMachine.compPerTick
+		if qlen < minQlen {
 			indToUse = ind
-			minQlen = idleMachine.qlen
+			minQlen = qlen
 		}
 	}
 
@@ -79,9 +91,11 @@ type MineGSS struct {
 	nProcGenPerTick int
 	nFoundIdle      int
 	nUsedKChoices   int
+	net             *Network
+	toReq           []*Proc // procs that came back (killed, or their placement failed), to go back in the multiq
 }
 
-func newMineGSS(id int, machines map[Tid]*Machine, currTickPtr *Tftick, numGenPerTick int, idleHeap *IdleHeap) *MineGSS {
+func newMineGSS(id int, machines map[Tid]*Machine, currTickPtr *Tftick, numGenPerTick int, idleHeap *IdleHeap, net *Network) *MineGSS {
 	gs := &MineGSS{
 		gsId:            Tid(id),
 		machines:        machines,
@@ -91,6 +105,8 @@ func newMineGSS(id int, machines map[Tid]*Machine, currTickPtr *Tftick, numGenPe
 		nProcGenPerTick: numGenPerTick,
 		nFoundIdle:      0,
 		nUsedKChoices:   0,
+		net:             net,
+		toReq:           make([]*Proc, 0),
 	}
 
 	return gs
@@ -104,6 +120,16 @@ func (gs *MineGSS) MachinesString() string {
 	return str
 }
 
+func (gs *MineGSS) liveMachines() []*Machine {
+	live := make([]*Machine, 0, len(gs.machines))
+	for _, m := range gs.machines {
+		if !m.failed && !m.draining {
+			live = append(live, m)
+		}
+	}
+	return live
+}
+
 func (gs *MineGSS) placeProcs() {
 
 	// toWrite := fmt.Sprintf("%v, %v: q before placing procs: %v \n", *gs.currTickPtr, gs.gsId, gs.multiq.qMap)
@@ -111,60 +137,107 @@ func (gs *MineGSS) placeProcs() {
 
 	logWrite(SCHED, "\n")
 
+	// whatever came back since last time (over the network) can be placed again now
+	gs.requeue()
+
 	// setup
 	p := gs.multiq.deq(*gs.currTickPtr)
 
-	toReq := make([]*Proc, 0)
-
 	for p != nil {
 		// place given proc
 
-		machineToUse := gs.pickMachine(p)
+		machineToUse, probeTime := gs.pickMachine(p)
 
 		toWrite := fmt.Sprintf("%v, GS %v placing proc %v; curr idle heap: %v \n", int(*gs.currTickPtr), gs.gsId, p.procId, gs.idleMachines.heap)
 		logWrite(SCHED, toWrite)
 
 		if machineToUse == nil {
 			logWrite(SCHED, "    -> nothing avail \n")
-			toReq = append(toReq, p)
+			gs.toReq = append(gs.toReq, p)
 			p = gs.multiq.deq(*gs.currTickPtr)
 			continue
 		}
 
-		shouldStoreIdleInfo, idleVal, procKilled := machineToUse.placeProc(p, gs.gsId)
-		toWrite = fmt.Sprintf("    -> chose %v; after placing should store: %v, new idle val: %v \n", machineToUse.machineId, shouldStoreIdleInfo, idleVal)
-		logWrite(SCHED, toWrite)
+		gs.sendPlacement(machineToUse, p, probeTime)
 
-		if procKilled != nil {
-			toReq = append(toReq, procKilled)
-		}
+		p = gs.multiq.deq(*gs.currTickPtr)
+	}
+
+	gs.requeue()
 
-		if shouldStoreIdleInfo {
+}
+
+func (gs *MineGSS) requeue() {
+	for _, p := range gs.toReq {
+		gs.multiq.enq(p)
+	}
+	gs.toReq = make([]*Proc, 0)
+}
+
+// the placement is a message to the machine, and the machine's answer (its new idle info, and
+// what it killed to make room) is a message back
+func (gs *MineGSS) sendPlacement(machineToUse *Machine, p *Proc, after Tftick) {
+
+	place := func() {
+
+		// the GS's view was stale, the machine isn't taking procs anymore; if the proc was being moved
+		// that is over now, and its next placement is a real one
+		if machineToUse.failed || machineToUse.draining {
+			logWrite(SCHED, fmt.Sprintf("    -> %v bounced proc %v \n", machineToUse.machineId, p.procId))
+			p.migrating = false
+			gs.toReq = append(gs.toReq, p)
 			if contains(gs.idleMachines.heap, machineToUse.machineId) {
 				remove(gs.idleMachines.heap, machineToUse.machineId)
 			}
-			if idleVal.memAvail > IDLE_HEAP_MEM_THRESHOLD {
-				gs.idleMachines.heap.Push(idleVal)
-			}
+			return
 		}
 
-		p = gs.multiq.deq(*gs.currTickPtr)
-	}
+		shouldStoreIdleInfo, idleVal, procsKilled := machineToUse.placeProc(p, gs.gsId)
+		toWrite := fmt.Sprintf("    -> chose %v; after placing should store: %v, new idle val: %v \n", machineToUse.machineId, shouldStoreIdleInfo, idleVal)
+		logWrite(SCHED, toWrite)
 
-	for _, p := range toReq {
-		gs.multiq.enq(p)
+		// if the reply is lost the GS still has to find out about the killed procs eventually, or they
+		// would just disappear
+		requeueKilled := func() {
+			gs.toReq = append(gs.toReq, procsKilled...)
+		}
+
+		gs.net.send(MSG_PLACE_REPLY, 0, func() {
+			requeueKilled()
+
+			if shouldStoreIdleInfo {
+				if contains(gs.idleMachines.heap, machineToUse.machineId) {
+					remove(gs.idleMachines.heap, machineToUse.machineId)
+				}
+				if idleVal.memAvail > IDLE_HEAP_MEM_THRESHOLD {
+					gs.idleMachines.heap.Push(idleVal)
+				}
+			}
+		}, requeueKilled)
 	}
 
+	gs.net.send(MSG_PLACE, after, place, func() {
+		p.migrating = false
+		gs.toReq = append(gs.toReq, p)
+	})
 }
 
-func (gs *MineGSS) pickMachine(procToPlace *Proc) *Machine {
+// the best idle machine if the heap has one, otherwise the one of k choices that makes its money back
+// the soonest; the probe time is as for HermodGS.pickMachine
+func (gs *MineGSS) pickMachine(procToPlace *Proc) (*Machine, Tftick) {
+
+	// the heap can be stale, and have machines in it that are already gone
+	isWarm := func(mid Tid) bool {
+		m, ok := gs.machines[mid]
+		return ok && m.warm.isWarm(procToPlace)
+	}
 
 	gs.idleMachines.lock.Lock()
-	machine, found := useBestIdle(gs.idleMachines.heap, procToPlace.maxMem())
+	machine, found := useBestIdle(gs.idleMachines.heap, procToPlace.maxMem(), isWarm)
 	gs.idleMachines.lock.Unlock()
-	if found {
+	if m, ok := gs.machines[machine.machine]; found && ok {
 		gs.nFoundIdle += 1
-		return gs.machines[machine.machine]
+		return m, 0
 	}
 
 	// actualMemFree := make([]Tmem, len(gs.machines))
@@ -177,12 +250,18 @@ func (gs *MineGSS) pickMachine(procToPlace *Proc) *Machine {
 
 	// if no idle machine, use power of k choices
 	var machineToUse *Machine
-	machineToTry := pickRandomElements(Values(gs.machines), K_CHOICES_DOWN)
+	machineToTry := pickRandomElements(gs.liveMachines(), K_CHOICES_DOWN)
 
 	minTimeToProfit := float32(math.MaxFloat32)
+	probeTime := Tftick(0)
 
 	for _, m := range machineToTry {
-		timeToProfit := m.okToPlace(procToPlace)
+		timeToProfit, answered, rtt := probe(gs.net, func() float32 { return m.okToPlace(procToPlace) })
+		if !answered {
+			continue
+		}
+		probeTime = Tftick(math.Max(float64(probeTime), float64(rtt)))
+
 		if timeToProfit < minTimeToProfit {
 			minTimeToProfit = timeToProfit
 			machineToUse = m
@@ -190,11 +269,11 @@ func (gs *MineGSS) pickMachine(procToPlace *Proc) *Machine {
 	}
 
 	if minTimeToProfit > TIME_TO_PROFIT_THRESHOLD {
-		return nil
+		return nil, 0
 	}
 
 	// toWrite = fmt.Sprintf("   used k choices: the machine to use is %v \n", machineToUse)
 	// logWrite(SCHED, toWrite)
 
-	return machineToUse
+	return machineToUse, probeTime
 }
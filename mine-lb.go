@@ -1,39 +1,63 @@
 package slasched
 
+import (
+	"sort"
+)
+
 type MineLB struct {
 	currTickPtr *Tftick
+	ctx         *LBCtx
 
 	machines      map[Tid]*Machine
 	GSSs          []*MineGSS
 	roundRobinInd int
+
+	// for the autoscaler to add machines with
+	fleet         FleetSpec
+	idleHeaps     map[Tid]*IdleHeap
+	nGenPerTick   int
+	nextMachineId Tid
+	classesAdded  []int // per class in fleet, how many machines the autoscaler added
 }
 
-func newMineLB(numMachines int, numCores int, nGenPerTick int, nGSSs int, currTickPtr *Tftick) *MineLB {
+func newMineLB(fleet FleetSpec, nGenPerTick int, nGSSs int, currTickPtr *Tftick, ctx *LBCtx) *MineLB {
 
 	mlb := &MineLB{
 		currTickPtr:   currTickPtr,
+		ctx:           ctx,
 		machines:      map[Tid]*Machine{},
 		GSSs:          make([]*MineGSS, nGSSs),
 		roundRobinInd: 0,
+		fleet:         fleet,
+		classesAdded:  make([]int, len(fleet)),
+		nGenPerTick:   nGenPerTick,
 	}
 
+	ctx.enqProc = mlb.enqProc
+
 	idleHeaps := make(map[Tid]*IdleHeap, nGSSs)
 	for i := 0; i < nGSSs; i++ {
 		idleHeap := &IdleHeap{
 			heap: &MinHeap{},
 		}
 		idleHeaps[Tid(i)] = idleHeap
-		mlb.GSSs[i] = newMineGSS(i, mlb.machines, mlb.currTickPtr, nGenPerTick, idleHeap)
+		mlb.GSSs[i] = newMineGSS(i, mlb.machines, mlb.currTickPtr, nGenPerTick, idleHeap, ctx.net)
 	}
 
-	for i := 0; i < numMachines; i++ {
+	for i, mc := range fleet.machineClasses() {
 		mid := Tid(i)
-		mlb.machines[Tid(i)] = newMachine(mid, idleHeaps, numCores, mlb.currTickPtr, nGenPerTick)
+		mlb.machines[Tid(i)] = newMachine(mid, idleHeaps, mc.numCores, mc.mem, mc.speed, mlb.currTickPtr, nGenPerTick, mlb.ctx)
 	}
+	mlb.idleHeaps = idleHeaps
+	mlb.nextMachineId = Tid(len(mlb.machines))
 
 	return mlb
 }
 
+func (mlb *MineLB) getCtx() *LBCtx {
+	return mlb.ctx
+}
+
 func (mlb *MineLB) enqProc(provProc *Proc) {
 
 	mlb.GSSs[mlb.roundRobinInd].multiq.enq(provProc)
@@ -46,6 +70,8 @@ func (mlb *MineLB) enqProc(provProc *Proc) {
 
 func (mlb *MineLB) placeProcs() {
 
+	mlb.ctx.net.deliver(*mlb.currTickPtr)
+
 	for _, gs := range mlb.GSSs {
 		gs.placeProcs()
 	}
@@ -53,7 +79,102 @@ func (mlb *MineLB) placeProcs() {
 }
 
 func (mlb *MineLB) tick() {
-	for _, m := range mlb.machines {
+
+	failed, recovered := mlb.ctx.failures.tick(*mlb.currTickPtr, Keys(mlb.machines))
+	for _, mid := range recovered {
+		mlb.machines[mid].recover()
+		mlb.ctx.machineRecovered(mid)
+	}
+	for _, mid := range failed {
+		mlb.failMachine(mid)
+	}
+
+	if AUTOSCALE {
+		mlb.autoscale()
+	}
+
+	if WORK_STEALING {
+		mlb.stealWork()
+	}
+
+	if DEFRAG {
+		mlb.defrag()
+	}
+
+	for mid, m := range mlb.machines {
+		if m.failed {
+			continue
+		}
 		m.tick()
+		if m.draining && m.activeQ.qlen() == 0 {
+			mlb.removeMachine(mid)
+		}
+	}
+}
+
+func (mlb *MineLB) autoscale() {
+
+	coresIdle, cores, backlog, nDraining := 0.0, 0.0, 0, 0
+	active := make([]*Machine, 0, len(mlb.machines))
+	for _, m := range mlb.machines {
+		if m.draining {
+			nDraining += 1
+		}
+		if m.failed || m.draining {
+			continue
+		}
+		active = append(active, m)
+		coresIdle += float64(m.lastTicksIdle)
+		cores += float64(m.numCores)
+	}
+	for _, gs := range mlb.GSSs {
+		backlog += gs.multiq.len()
+	}
+
+	util := 1.0
+	if cores > 0 {
+		util = 1 - coresIdle/cores
+	}
+	nReady, nToDrain := mlb.ctx.autoscaler.tick(mlb.ctx, util, backlog, len(active), nDraining)
+
+	// new machines keep to the mix of classes in the fleet, and register in an idle heap just like
+	// the ones we started with
+	for i := 0; i < nReady; i++ {
+		class := mlb.fleet.classToAdd(mlb.classesAdded)
+		mlb.classesAdded[class] += 1
+		mc := mlb.fleet[class]
+		mlb.machines[mlb.nextMachineId] = newMachine(mlb.nextMachineId, mlb.idleHeaps, mc.numCores, mc.mem, mc.speed, mlb.currTickPtr, mlb.nGenPerTick, mlb.ctx)
+		mlb.nextMachineId += 1
+	}
+
+	// drain the emptiest machines
+	sort.Slice(active, func(i, j int) bool {
+		if active[i].activeQ.qlen() == active[j].activeQ.qlen() {
+			return active[i].machineId < active[j].machineId
+		}
+		return active[i].activeQ.qlen() < active[j].activeQ.qlen()
+	})
+	for i := 0; i < nToDrain && i < len(active); i++ {
+		active[i].drain()
+	}
+}
+
+// a drained machine might still be sitting in a heap a GS pushed it into, so check all of them
+func (mlb *MineLB) removeMachine(mid Tid) {
+	for _, idleHeap := range mlb.idleHeaps {
+		idleHeap.lock.Lock()
+		if contains(idleHeap.heap, mid) {
+			remove(idleHeap.heap, mid)
+		}
+		idleHeap.lock.Unlock()
+	}
+	delete(mlb.machines, mid)
+}
+
+func (mlb *MineLB) failMachine(mid Tid) {
+	mlb.ctx.machineFailed()
+	for _, p := range mlb.machines[mid].fail() {
+		mlb.ctx.procLost(p)
+		mlb.GSSs[p.gsId].multiq.enq(p)
 	}
 }
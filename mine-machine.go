@@ -17,27 +17,54 @@ type Tprocmap map[Tid]int
 type Machine struct {
 	machineId               Tid
 	numCores                int
+	totalMem                Tmem
+	speed                   float64
 	activeQ                 *Queue
 	idleHeaps               map[Tid]*IdleHeap
 	currHeapGSS             Tid
+	failed                  bool
+	draining                bool   // the autoscaler is taking the machine away once it is empty
+	lastTicksIdle           Tftick // core ticks nothing ran on in the last tick
+	coreHist                CoreHistory
+	warm                    *WarmCache
+	power                   *PowerState
 	currTickPtr             *Tftick
 	worldNumProcsGenPerTick int
+	ctx                     *LBCtx
 }
 
-func newMachine(mid Tid, idleHeaps map[Tid]*IdleHeap, numCores int, currTickPtr *Tftick, nGenPerTick int) *Machine {
+func newMachine(mid Tid, idleHeaps map[Tid]*IdleHeap, numCores int, totMem Tmem, speed float64, currTickPtr *Tftick, nGenPerTick int, ctx *LBCtx) *Machine {
 
 	sd := &Machine{
 		machineId:               mid,
 		numCores:                numCores,
+		totalMem:                totMem,
+		speed:                   speed,
 		activeQ:                 newQueue(),
 		idleHeaps:               idleHeaps,
 		currHeapGSS:             -1,
 		currTickPtr:             currTickPtr,
 		worldNumProcsGenPerTick: nGenPerTick,
+		ctx:                     ctx,
+		coreHist:                CoreHistory{},
+		warm:                    newWarmCache(totMem),
+		power:                   newPowerState(),
 	}
 
-	// add machine to an idle heap
+	sd.joinIdleHeap()
+
+	return sd
+}
+
+func (sd *Machine) String() string {
+	return fmt.Sprintf("machine scheduler: %v", sd.machineId)
+}
+
+// add (empty) machine to an idle heap
+func (sd *Machine) joinIdleHeap() {
+
 	heapsToLookAt := pickRandomElementsMap(sd.idleHeaps, K_CHOICES_UP)
+	sd.ctx.net.count(MSG_HEAP_PROBE, len(heapsToLookAt))
 
 	var gsHeapToUse Tid
 	minLength := math.MaxInt
@@ -52,25 +79,64 @@ func newMachine(mid Tid, idleHeaps map[Tid]*IdleHeap, numCores int, currTickPtr
 	}
 
 	sd.currHeapGSS = gsHeapToUse
-	heapToUse := sd.idleHeaps[gsHeapToUse]
-	heapToUse.lock.Lock()
-	toPush := TIdleMachine{
-		memAvail:           MEM_PER_MACHINE,
-		highestCostRunning: -1,
-		qlen:               0,
-		machine:            Tid(sd.machineId),
+	sd.pushIdle(sd.idleHeaps[gsHeapToUse], sd.idleVal(-1))
+}
+
+func (sd *Machine) leaveIdleHeap() {
+	if sd.currHeapGSS >= 0 {
+		heapToUse := sd.idleHeaps[sd.currHeapGSS]
+		sd.ctx.net.send(MSG_IDLE_REMOVE, 0, func() {
+			heapToUse.lock.Lock()
+			if contains(heapToUse.heap, sd.machineId) {
+				remove(heapToUse.heap, sd.machineId)
+			}
+			heapToUse.lock.Unlock()
+		}, nil)
+		sd.currHeapGSS = -1
 	}
-	heapToUse.heap.Push(toPush)
-	heapToUse.lock.Unlock()
+}
 
-	return sd
+// tell the GS whose heap it is about the machine's idle info; if the machine is already in there,
+// the new info replaces the old
+func (sd *Machine) pushIdle(heapToUse *IdleHeap, val TIdleMachine) {
+	sd.ctx.net.send(MSG_IDLE_PUSH, 0, func() {
+		heapToUse.lock.Lock()
+		if contains(heapToUse.heap, sd.machineId) {
+			remove(heapToUse.heap, sd.machineId)
+		}
+		heapToUse.heap.Push(val)
+		heapToUse.lock.Unlock()
+	}, nil)
 }
 
-func (sd *Machine) String() string {
-	return fmt.Sprintf("machine scheduler: %v", sd.machineId)
+// the machine goes down, and all of the procs on it are lost, as are its warm instances
+func (sd *Machine) fail() []*Proc {
+	sd.failed = true
+	sd.leaveIdleHeap()
+
+	lost := sd.activeQ.getQ()
+	sd.activeQ = newQueue()
+	sd.coreHist = CoreHistory{}
+	sd.warm = newWarmCache(sd.totalMem)
+
+	return lost
+}
+
+// stop taking new procs; the LB removes the machine once what is on it is done
+func (sd *Machine) drain() {
+	sd.draining = true
+	sd.leaveIdleHeap()
+}
+
+func (sd *Machine) recover() {
+	sd.failed = false
+	if !sd.draining {
+		sd.joinIdleHeap()
+	}
 }
 
 func (sd *Machine) tick() {
+	sd.ctx.swapIn(sd.activeQ.getQ(), sd.memFree())
 	sd.simulateRunProcs()
 }
 
@@ -79,40 +145,92 @@ func (sd *Machine) memFree() Tmem {
 	memUsed := Tmem(0)
 
 	for _, p := range sd.activeQ.getQ() {
-		memUsed += p.maxMem()
+		memUsed += p.memCharged()
+	}
+	return sd.totalMem - memUsed - sd.warm.mem()
+}
+
+// what the machine tells the GSS whose idle heap it is in
+func (sd *Machine) idleVal(highestCost float32) TIdleMachine {
+	return TIdleMachine{
+		machine:            sd.machineId,
+		highestCostRunning: highestCost,
+		qlen:               sd.activeQ.qlen(),
+		memAvail:           sd.memFree(),
+		compPerTick:        float64(sd.numCores) * sd.speed,
+	}
+}
+
+// kill procs until what they are actually using fits in physical memory again
+func (sd *Machine) checkOOM() {
+	for physMemUsed(sd.activeQ.getQ())+sd.warm.mem() > sd.totalMem {
+		if sd.warm.drop() {
+			continue
+		}
+		victim := pickOOMVictim(sd.activeQ.getQ())
+		sd.activeQ.kill(victim.procId)
+
+		toWrite := fmt.Sprintf("   -> OOM killed %v \n", victim.String())
+		logWrite(SCHED, toWrite)
+
+		sd.ctx.oomKilled(victim)
 	}
-	return MEM_PER_MACHINE - memUsed
 }
 
 func (sd *Machine) okToPlace(newProc *Proc) float32 {
 
-	// if it just fits in terms of memory do it
-	if newProc.maxMem() < sd.memFree() {
-		return 0
+	// a cold start is time the proc isn't making progress either
+	coldPenalty := float32(0)
+	if !sd.warm.isWarm(newProc) {
+		coldPenalty = COLD_START_DELAY
 	}
 
-	// if it doesn't fit, look if there a good proc to kill? (/a combination of procs? can add that later)
-	_, minTimeToProfit := sd.activeQ.checkKill(newProc)
+	// if it just fits in terms of memory do it; the warm instances can all make way for it
+	memFree := sd.memFree() + sd.warm.mem()
+	if newProc.maxMem() < memFree {
+		return coldPenalty
+	}
 
-	return minTimeToProfit
+	// if it doesn't fit, look if there are good procs to kill
+	_, minTimeToProfit := sd.activeQ.checkKill(newProc, memFree)
+
+	return minTimeToProfit + coldPenalty
 }
 
-func (sd *Machine) placeProc(newProc *Proc, fromGs Tid) (bool, TIdleMachine, *Proc) {
+func (sd *Machine) placeProc(newProc *Proc, fromGs Tid) (bool, TIdleMachine, []*Proc) {
 
 	newProc.timePlaced = *sd.currTickPtr
+	newProc.gsId = fromGs
+	if !newProc.migrating {
+		sd.ctx.procPlaced(newProc)
+	}
+	newProc.migrating = false
 
-	var killed *Proc
+	var killed []*Proc
 
 	ogMemFree := sd.memFree()
+	memFree := sd.warm.makeRoom(newProc, ogMemFree)
 
-	if newProc.maxMem() < sd.memFree() {
+	sd.ctx.startInstance(newProc, sd.warm)
+	sd.ctx.restore(newProc)
+	sd.ctx.wake(sd.power, newProc)
+
+	if newProc.maxMem() < memFree {
 		sd.activeQ.enq(newProc)
 
 	} else {
-		// if it doesn't fit, look if there a good proc to kill? (/a combination of procs? can add that later)
-		procToKill, _ := sd.activeQ.checkKill(newProc)
-
-		killed = sd.activeQ.kill(procToKill)
+		// if it doesn't fit, look if there are good procs to kill
+		victims, _ := sd.activeQ.checkKill(newProc, memFree)
+		for _, p := range victims {
+			if sd.ctx.evict(p) {
+				sd.activeQ.kill(p.procId)
+				killed = append(killed, p)
+			}
+		}
+		if len(victims) > 0 {
+			sd.ctx.stats.inc("placement_kills", 1)
+			sd.ctx.evicted(victims)
+		}
 
 		sd.activeQ.enq(newProc)
 	}
@@ -126,20 +244,7 @@ func (sd *Machine) placeProc(newProc *Proc, fromGs Tid) (bool, TIdleMachine, *Pr
 
 	// if not from GS whose list we're in and change in mem is large, update the list
 	if (float32(sd.memFree()) < 0.9*float32(ogMemFree)) && (sd.currHeapGSS >= 0) && (sd.currHeapGSS != fromGs) {
-		heapToUse := sd.idleHeaps[sd.currHeapGSS]
-		heapToUse.lock.Lock()
-		// also if it is already in the heap, then replace it with the new value
-		if contains(heapToUse.heap, sd.machineId) {
-			remove(heapToUse.heap, sd.machineId)
-		}
-		toPush := TIdleMachine{
-			machine:            sd.machineId,
-			highestCostRunning: maxCostRunning,
-			qlen:               sd.activeQ.qlen(),
-			memAvail:           sd.memFree(),
-		}
-		heapToUse.heap.Push(toPush)
-		heapToUse.lock.Unlock()
+		sd.pushIdle(sd.idleHeaps[sd.currHeapGSS], sd.idleVal(maxCostRunning))
 	}
 
 	// don't want the GSS to take out idleness into account if we are already somewhere else
@@ -165,12 +270,7 @@ func (sd *Machine) placeProc(newProc *Proc, fromGs Tid) (bool, TIdleMachine, *Pr
 		sd.currHeapGSS = fromGs
 	}
 
-	return true, TIdleMachine{
-		machine:            sd.machineId,
-		highestCostRunning: maxCostRunning,
-		qlen:               sd.activeQ.qlen(),
-		memAvail:           sd.memFree(),
-	}, killed
+	return true, sd.idleVal(maxCostRunning), killed
 }
 
 // do numCores ticks of computation (only on procs in the activeQ)
@@ -212,6 +312,9 @@ func (sd *Machine) simulateRunProcs() {
 	toWrite = fmt.Sprintf("\n==> %v @ %v, machine %v (on heap: %v, mem free: %v); has q: \n%v", sd.worldNumProcsGenPerTick, sd.currTickPtr.String(), sd.machineId, sd.currHeapGSS, sd.memFree(), sd.activeQ.SummaryString())
 	logWrite(SCHED, toWrite)
 
+	// procs blocked on I/O keep their memory, but leave the cores to the others for this tick
+	blocked := sd.activeQ.removeBlocked(*sd.currTickPtr)
+
 	for sd.activeQ.qlen() > 0 && totalTicksLeftToGive-Tftick(TICK_SCHED_THRESHOLD) > 0.0 && len(coresWithTicksLeft) > 0 {
 
 		for i := 0; i < sd.numCores; i++ {
@@ -246,25 +349,29 @@ func (sd *Machine) simulateRunProcs() {
 			toWrite := fmt.Sprintf("   core %v giving %v to proc %v \n", currCore, ticksLeftPerCore[currCore], procToRun.String())
 			logWrite(SCHED, toWrite)
 
-			ticksUsed, done := procToRun.runTillOutOrDone(ticksLeftPerCore[currCore])
+			overhead := sd.ctx.switchOverhead(procToRun, sd.machineId, currCore, sd.coreHist, ticksLeftPerCore[currCore])
+			ticksUsed, done := procToRun.runAtSpeed(ticksLeftPerCore[currCore]-overhead, sd.speed)
 
-			ticksLeftPerCore[currCore] -= ticksUsed
-			totalTicksLeftToGive -= ticksUsed
+			ticksLeftPerCore[currCore] -= ticksUsed + overhead
+			totalTicksLeftToGive -= ticksUsed + overhead
 
 			if ticksLeftPerCore[currCore] < TICK_SCHED_THRESHOLD {
 				delete(coresWithTicksLeft, currCore)
 			}
 
 			if !done {
+				procToRun.blockIfAtIO(*sd.currTickPtr + (1 - ticksLeftPerCore[currCore]))
 				toReq = append(toReq, procToRun)
 			} else {
 				// if the proc is done, update the ticksPassed to be exact for metrics etc
 				procToRun.timeDone = *sd.currTickPtr + (1 - ticksLeftPerCore[currCore])
+				sd.ctx.procDone(procToRun)
+				sd.warm.release(procToRun)
 
 				toWrite := fmt.Sprintf("   -> done: %v\n", procToRun.String())
 				logWrite(SCHED, toWrite)
 
-				if (procToRun.timeDone - procToRun.timeStarted) > procToRun.compDone {
+				if procToRun.timeDone > procToRun.deadline() {
 					toWrite := fmt.Sprintf("   ---> OVER %v \n", procToRun.String())
 					logWrite(SCHED, toWrite)
 				}
@@ -279,6 +386,11 @@ func (sd *Machine) simulateRunProcs() {
 	for _, p := range toReq {
 		sd.activeQ.enq(p)
 	}
+	for _, p := range blocked {
+		sd.activeQ.enq(p)
+	}
+
+	sd.checkOOM()
 
 	if totalTicksLeftToGive < 0.00002 {
 		totalTicksLeftToGive = 0
@@ -286,6 +398,9 @@ func (sd *Machine) simulateRunProcs() {
 	toWrite = fmt.Sprintf(", %.3f, %v\n", float64(math.Copysign(float64(totalTicksLeftToGive), 1)), ogMemFree)
 	logWrite(USAGE, toWrite)
 
+	sd.lastTicksIdle = totalTicksLeftToGive
+	sd.ctx.machineTicked(sd.power, sd.numCores, totalTicksLeftToGive, physMemUsed(sd.activeQ.getQ())+sd.warm.mem())
+
 	highestCost := float32(0)
 	for _, p := range sd.activeQ.getQ() {
 		if p.willingToSpend() > highestCost {
@@ -293,16 +408,9 @@ func (sd *Machine) simulateRunProcs() {
 		}
 	}
 
-	if (sd.activeQ.qlen() > IDLE_HEAP_QLEN_THRESHOLD) && (sd.memFree() < IDLE_HEAP_MEM_THRESHOLD) {
+	if sd.draining || ((sd.activeQ.qlen() > IDLE_HEAP_QLEN_THRESHOLD) && (sd.memFree() < IDLE_HEAP_MEM_THRESHOLD)) {
 		// are not idle
-		if sd.currHeapGSS >= 0 {
-			sd.idleHeaps[sd.currHeapGSS].lock.Lock()
-			if contains(sd.idleHeaps[sd.currHeapGSS].heap, sd.machineId) {
-				remove(sd.idleHeaps[sd.currHeapGSS].heap, sd.machineId)
-			}
-			sd.idleHeaps[sd.currHeapGSS].lock.Unlock()
-			sd.currHeapGSS = -1
-		}
+		sd.leaveIdleHeap()
 		return
 	}
 
@@ -314,6 +422,7 @@ func (sd *Machine) simulateRunProcs() {
 	} else {
 		// choose idle heap to use by power of k choices
 		heapsToLookAt := pickRandomElementsMap(sd.idleHeaps, K_CHOICES_UP)
+		sd.ctx.net.count(MSG_HEAP_PROBE, len(heapsToLookAt))
 
 		minLength := math.MaxInt
 		for gssId, possHeap := range heapsToLookAt {
@@ -327,18 +436,6 @@ func (sd *Machine) simulateRunProcs() {
 		}
 	}
 
-	heapToUse.lock.Lock()
-	// also if it is already in the heap, then replace it with the new value
-	if contains(heapToUse.heap, sd.machineId) {
-		remove(heapToUse.heap, sd.machineId)
-	}
-	toPush := TIdleMachine{
-		machine:            sd.machineId,
-		highestCostRunning: highestCost,
-		qlen:               sd.activeQ.qlen(),
-		memAvail:           sd.memFree(),
-	}
-	heapToUse.heap.Push(toPush)
-	heapToUse.lock.Unlock()
+	sd.pushIdle(heapToUse, sd.idleVal(highestCost))
 
 }
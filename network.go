@@ -0,0 +1,169 @@
+package slasched
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+type MsgType string
+
+const (
+	MSG_IDLE_PUSH   MsgType = "idle_push"   // machine -> GS: add or update the machine in the GS's idle heap
+	MSG_IDLE_REMOVE MsgType = "idle_remove" // machine -> GS: take the machine out of the GS's idle heap
+	MSG_PROBE       MsgType = "probe"       // GS -> machine and back: k-choices asking how a proc would fit
+	MSG_PLACE       MsgType = "place"       // GS -> machine: run this proc
+	MSG_PLACE_REPLY MsgType = "place_reply" // machine -> GS: placed it, here is my new idle info (and what I killed)
+	MSG_HEAP_PROBE  MsgType = "heap_probe"  // machine -> GS: how long is your idle heap (not delayed, just counted)
+	MSG_MIGRATE     MsgType = "migrate"     // machine -> machine: a running proc, see defrag.go (not delayed, just counted)
+
+	// sparrow, see sparrow-sched.go
+	MSG_RESERVE  MsgType = "reserve"  // scheduler -> machine: a reservation for one of the scheduler's batches
+	MSG_GET_TASK MsgType = "get_task" // machine -> scheduler: I have a core free, which proc should I run
+	MSG_TASK     MsgType = "task"     // scheduler -> machine: this one (or none, the batch is all launched)
+	MSG_CANCEL   MsgType = "cancel"   // scheduler -> machine: drop that reservation, the batch is all launched
+)
+
+var msgTypes = []MsgType{MSG_IDLE_PUSH, MSG_IDLE_REMOVE, MSG_PROBE, MSG_PLACE, MSG_PLACE_REPLY, MSG_HEAP_PROBE, MSG_MIGRATE, MSG_RESERVE, MSG_GET_TASK, MSG_TASK, MSG_CANCEL}
+
+type Msg struct {
+	deliverAt Tftick
+	seq       int // so that messages due at the same time are delivered in the order they were sent
+	deliver   func()
+}
+
+// the control plane between an LB's GSs and its machines. If there is no delay, jitter or loss it
+// is synchronous, ie every message is delivered right when it is sent, which is what the LBs did
+// before there was a network. Otherwise messages are delivered at the start of the first
+// placeProcs after they arrive, so any delay at all is at least a tick
+type Network struct {
+	rand     *rand.Rand
+	ctx      *LBCtx
+	inFlight []*Msg
+	nSent    int
+}
+
+func newNetwork(ctx *LBCtx) *Network {
+	return &Network{
+		rand:     rand.New(rand.NewSource(SEED)),
+		ctx:      ctx,
+		inFlight: make([]*Msg, 0),
+	}
+}
+
+func (n *Network) synchronous() bool {
+	return NET_DELAY == 0 && NET_JITTER == 0 && NET_LOSS == 0
+}
+
+func (n *Network) delay() Tftick {
+	return Tftick(NET_DELAY + n.rand.Float64()*NET_JITTER)
+}
+
+// every logical message is counted, whether or not it makes it
+func (n *Network) count(kind MsgType, amt int) {
+	n.ctx.stats.inc("msgs_"+string(kind), float64(amt))
+}
+
+func (n *Network) lost(kind MsgType) bool {
+	if NET_LOSS > 0 && n.rand.Float64() < NET_LOSS {
+		n.ctx.stats.inc("msgs_lost_"+string(kind), 1)
+		return true
+	}
+	return false
+}
+
+func (n *Network) enq(at Tftick, deliver func()) {
+	n.inFlight = append(n.inFlight, &Msg{deliverAt: at, seq: n.nSent, deliver: deliver})
+	n.nSent += 1
+}
+
+// send a message that gets delivered after the network's delay plus after (eg because the sender
+// first had to wait on probes). If it is lost and onLost isn't nil, the sender notices after
+// NET_TIMEOUT and onLost runs then
+func (n *Network) send(kind MsgType, after Tftick, deliver func(), onLost func()) {
+	n.count(kind, 1)
+
+	if n.synchronous() {
+		deliver()
+		return
+	}
+
+	now := *n.ctx.currTickPtr
+	if n.lost(kind) {
+		if onLost != nil {
+			n.enq(now+after+NET_TIMEOUT, onLost)
+		}
+		return
+	}
+	n.enq(now+after+n.delay(), deliver)
+}
+
+// a probe is a round trip. The machine's answer is what it looks like when the probe is sent, and
+// the sender only gets it once the round trip is over, so by the time it acts on the answer the
+// machine may well have changed; returns the answer, whether it came back, and how long that took
+func probe[T any](n *Network, answer func() T) (T, bool, Tftick) {
+	n.count(MSG_PROBE, 1)
+
+	var ans T
+	if n.synchronous() {
+		return answer(), true, 0
+	}
+	if n.lost(MSG_PROBE) {
+		return ans, false, 0
+	}
+	return answer(), true, n.delay() + n.delay()
+}
+
+// deliver everything that has arrived by now
+func (n *Network) deliver(now Tftick) {
+
+	due := make([]*Msg, 0)
+	stillInFlight := make([]*Msg, 0, len(n.inFlight))
+	for _, m := range n.inFlight {
+		if m.deliverAt <= now {
+			due = append(due, m)
+		} else {
+			stillInFlight = append(stillInFlight, m)
+		}
+	}
+	n.inFlight = stillInFlight
+
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].deliverAt == due[j].deliverAt {
+			return due[i].seq < due[j].seq
+		}
+		return due[i].deliverAt < due[j].deliverAt
+	})
+
+	for _, m := range due {
+		m.deliver()
+	}
+}
+
+// how much each LB talks, overall and by type of message, per proc it placed and per tick
+func (ctx *LBCtx) writeMsgRates() {
+
+	nTicks := float64(*ctx.currTickPtr)
+	nPlaced := ctx.stats.get("procs_placed")
+
+	write := func(name string, nMsgs float64) {
+		toWrite := fmt.Sprintf("%v, %v, %v, %.3f\n", ctx.nGenPerTick, ctx.lbName, "msgs_per_tick_"+name, nMsgs/nTicks)
+		logWrite(LB_STATS, toWrite)
+		if nPlaced > 0 {
+			toWrite = fmt.Sprintf("%v, %v, %v, %.3f\n", ctx.nGenPerTick, ctx.lbName, "msgs_per_proc_"+name, nMsgs/nPlaced)
+			logWrite(LB_STATS, toWrite)
+		}
+	}
+
+	total := 0.0
+	for _, kind := range msgTypes {
+		nMsgs := ctx.stats.get("msgs_" + string(kind))
+		total += nMsgs
+		if nMsgs > 0 {
+			write(string(kind), nMsgs)
+		}
+	}
+	if nTicks > 0 {
+		write("total", total)
+	}
+}
@@ -0,0 +1,27 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// a probe's answer is what the machine looked like when it was sent, and doesn't change along with
+// the machine afterwards
+func TestProbeSnapshot(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 8)
+	m := newHermodMachine(0, 4, MEM_PER_MACHINE, 1, &currTick, 0, ctx, LOCAL_PS)
+	p := newTestProc(0, 10, 1, 100)
+
+	ans, answered, _ := probe(ctx.net, func() HermodProbe { return m.probeAnswer(p) })
+	if !answered {
+		t.Fatalf("probe on a synchronous network wasn't answered")
+	}
+	m.placeProc(p)
+
+	if ans.load != 0 || ans.nProcs != 0 || ans.memFree != MEM_PER_MACHINE {
+		t.Fatalf("answer %+v changed along with the machine", ans)
+	}
+	if got := ctx.stats.get("msgs_" + string(MSG_PROBE)); got != 1 {
+		t.Fatalf("%v probes counted, want 1", got)
+	}
+}
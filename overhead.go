@@ -0,0 +1,55 @@
+package slasched
+
+// the last proc each core of a machine ran, to tell context switches apart from a proc just
+// continuing where it left off
+type CoreHistory map[int]*Proc
+
+// what switching a core to a proc costs, see switchOverhead
+type SwitchCosts struct {
+	ctxSwitch Tftick
+	preempt   Tftick
+	migration Tftick
+}
+
+// called by the machines right before they give a core to a proc. Charges whatever switching to
+// the proc costs (a context switch if the core was running something else, a preemption if that
+// something else wasn't done yet, and a migration if the proc last ran on a different core or
+// machine), and returns how much of the ticksLeft on the core that used up
+func (ctx *LBCtx) switchOverhead(p *Proc, mid Tid, core int, hist CoreHistory, ticksLeft Tftick) Tftick {
+
+	costs := ctx.switchCosts
+	if costs == (SwitchCosts{}) {
+		return 0
+	}
+
+	cost := Tftick(0)
+
+	prev := hist[core]
+	if prev != p {
+		cost += costs.ctxSwitch
+		ctx.stats.inc("ctx_switches", 1)
+
+		// the core's last proc could have kept going: it wasn't done and didn't block on I/O
+		if prev != nil && prev.compDone < prev.procInternals.actualComp && prev.runnable(*ctx.currTickPtr) {
+			cost += costs.preempt
+			ctx.stats.inc("preemptions", 1)
+		}
+	}
+
+	if p.hasRun && (p.lastMachine != mid || p.lastCore != core) {
+		cost += costs.migration
+		ctx.stats.inc("core_migrations", 1)
+	}
+
+	hist[core] = p
+	p.hasRun = true
+	p.lastMachine = mid
+	p.lastCore = core
+
+	if cost > ticksLeft {
+		cost = ticksLeft
+	}
+	ctx.stats.inc("switch_overhead", float64(cost))
+
+	return cost
+}
@@ -0,0 +1,37 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// switching a core to a proc costs a context switch, a preemption on top if the core's last proc
+// could have kept going, and a migration on top if the proc last ran somewhere else; all of it is
+// capped at what the core has left
+func TestSwitchOverhead(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 2)
+	ctx.switchCosts = SwitchCosts{ctxSwitch: 0.1, preempt: 0.05, migration: 0.2}
+	hist := CoreHistory{}
+	p1, p2 := newTestProc(1, 10, 1, 100), newTestProc(2, 10, 1, 100)
+
+	for i, c := range []struct {
+		p         *Proc
+		core      int
+		ticksLeft Tftick
+		want      Tftick
+	}{
+		{p1, 0, 1, 0.1},     // first time on the core
+		{p1, 0, 1, 0},       // just keeps going
+		{p2, 0, 1, 0.15},    // p1 wasn't done
+		{p1, 1, 1, 0.3},     // p1 last ran on core 0
+		{p2, 1, 0.05, 0.05}, // would be 0.35, but the core only has 0.05 left
+	} {
+		if got := ctx.switchOverhead(c.p, 0, c.core, hist, c.ticksLeft); got-c.want > 0.0001 || c.want-got > 0.0001 {
+			t.Fatalf("switch %v cost %v, want %v", i, got, c.want)
+		}
+	}
+
+	if ctx.stats.get("ctx_switches") != 4 || ctx.stats.get("preemptions") != 2 || ctx.stats.get("core_migrations") != 2 {
+		t.Fatalf("counted %v switches, %v preemptions and %v migrations, want 4, 2 and 2", ctx.stats.get("ctx_switches"), ctx.stats.get("preemptions"), ctx.stats.get("core_migrations"))
+	}
+}
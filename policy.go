@@ -0,0 +1,51 @@
+package slasched
+
+import "sort"
+
+// how a machine orders the procs it has when it hands out core time
+type LocalPolicy int
+
+const (
+	LOCAL_PRICE      LocalPolicy = iota // highest willingToSpend first, see Queue.enq
+	LOCAL_PS                            // processor sharing, what hermod does
+	LOCAL_SRPT                          // least (guessed) remaining work first
+	LOCAL_PRICE_SRPT                    // least remaining work per dollar first
+	LOCAL_EDF                           // earliest (guessed) deadline first
+	LOCAL_FIFO                          // in the order they got to the machine, each to completion
+)
+
+// the guess of how much work the proc has left. Once it has run for longer than compGuess, use the
+// expected remaining work of a pareto that got this far, which is compDone/(alpha-1)
+func (p *Proc) remainingGuess() Tftick {
+	return max(p.compGuess()-p.compDone, p.compDone/(PARETO_ALPHA-1))
+}
+
+// the order the policy runs procs in, or nil if it isn't just an order
+func (lp LocalPolicy) less() func(a, b *Proc) bool {
+	switch lp {
+	case LOCAL_SRPT:
+		return func(a, b *Proc) bool { return a.remainingGuess() < b.remainingGuess() }
+	case LOCAL_PRICE_SRPT:
+		return func(a, b *Proc) bool {
+			return float32(a.remainingGuess())/a.willingToSpend() < float32(b.remainingGuess())/b.willingToSpend()
+		}
+	case LOCAL_EDF:
+		return func(a, b *Proc) bool { return a.deadlineGuess() < b.deadlineGuess() }
+	default:
+		return nil
+	}
+}
+
+// the runnable procs, in the order the policy wants to run them
+func (lp LocalPolicy) order(procs []*Proc, now Tftick) []*Proc {
+	runnable := make([]*Proc, 0, len(procs))
+	for _, p := range procs {
+		if p.runnable(now) {
+			runnable = append(runnable, p)
+		}
+	}
+	if less := lp.less(); less != nil {
+		sort.SliceStable(runnable, func(i, j int) bool { return less(runnable[i], runnable[j]) })
+	}
+	return runnable
+}
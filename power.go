@@ -0,0 +1,60 @@
+package slasched
+
+import "fmt"
+
+// a machine's power state; a machine that has been empty for POWER_OFF_AFTER ticks (or whatever the
+// LB's ctx says) turns off, and the next proc placed on it has to wait for it to wake up
+type PowerState struct {
+	emptyFor int
+	off      bool
+}
+
+func newPowerState() *PowerState {
+	return &PowerState{}
+}
+
+// called by the machines at the end of every tick
+func (ctx *LBCtx) machineTicked(ps *PowerState, nCores int, idle Tftick, memUsed Tmem) {
+	ctx.stats.inc("core_ticks", float64(nCores))
+	ctx.stats.inc("idle_core_ticks", float64(idle))
+
+	empty := idle >= Tftick(nCores) && memUsed == 0
+	if ctx.powerOffAfter > 0 && empty {
+		ps.emptyFor += 1
+		if ps.emptyFor >= ctx.powerOffAfter && !ps.off {
+			ps.off = true
+			ctx.stats.inc("power_offs", 1)
+		}
+	} else {
+		ps.emptyFor = 0
+	}
+
+	if ps.off {
+		ctx.stats.inc("off_core_ticks", float64(nCores))
+		ctx.stats.inc("energy", OFF_WATTS_PER_CORE*float64(nCores))
+		return
+	}
+
+	busy := float64(nCores) - float64(idle)
+	ctx.stats.inc("energy", BUSY_WATTS_PER_CORE*busy+IDLE_WATTS_PER_CORE*float64(idle)+WATTS_PER_MEM*float64(memUsed))
+}
+
+// called by the machines when they get a proc
+func (ctx *LBCtx) wake(ps *PowerState, p *Proc) {
+	ps.emptyFor = 0
+	if !ps.off {
+		return
+	}
+	ps.off = false
+	ctx.stats.inc("wakeups", 1)
+	p.blockedUntil = max(p.blockedUntil, *ctx.currTickPtr+WAKE_DELAY)
+}
+
+func (ctx *LBCtx) writeEnergy() {
+	energy := ctx.stats.get("energy")
+	if len(ctx.latencies) == 0 {
+		return
+	}
+	toWrite := fmt.Sprintf("%v, %v, %v, %.3f\n", ctx.nGenPerTick, ctx.lbName, "energy_per_proc", energy/float64(len(ctx.latencies)))
+	logWrite(LB_STATS, toWrite)
+}
@@ -0,0 +1,35 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// a machine that has been empty for long enough turns off, and the next proc it gets waits for it
+// to wake up before it runs
+func TestPowerOffAndWake(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 2)
+	ctx.powerOffAfter = 2
+	hm := newHermodMachine(0, 2, MEM_PER_MACHINE, 1, &currTick, 0, ctx, LOCAL_PS)
+
+	hm.tick()
+	if hm.power.off {
+		t.Fatalf("turned off after one empty tick, want two")
+	}
+	currTick = 1
+	hm.tick()
+	if !hm.power.off || ctx.stats.get("power_offs") != 1 || ctx.stats.get("off_core_ticks") != 2 {
+		t.Fatalf("machine not off after two empty ticks")
+	}
+
+	currTick = 2
+	p := newTestProc(0, 10, 1, 100)
+	hm.placeProc(p)
+	if hm.power.off || ctx.stats.get("wakeups") != 1 || p.blockedUntil != currTick+WAKE_DELAY {
+		t.Fatalf("proc placed on an off machine is blocked until %v, want %v", p.blockedUntil, currTick+WAKE_DELAY)
+	}
+	hm.tick()
+	if p.compDone != 0 {
+		t.Fatalf("proc ran while the machine was waking up")
+	}
+}
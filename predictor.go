@@ -0,0 +1,146 @@
+package slasched
+
+import (
+	"sort"
+)
+
+type PredictorType int
+
+const (
+	PRED_ORACLE PredictorType = iota // just use the compGuess the load generator hands out
+	PRED_EWMA                        // per-bucket exponentially weighted moving average
+	PRED_SKETCH                      // per-bucket quantile over a window of recent completions
+)
+
+type PredBucketType int
+
+const (
+	BUCKET_PRICE PredBucketType = iota // price class, as a stand-in for the tenant
+	BUCKET_MEM                         // size of the proc's memory reservation
+)
+
+// a runtime predictor estimates how much compute a proc will need when it arrives, and learns from
+// the procs that complete; each LB has its own so they only learn from what they themselves saw
+type RuntimePredictor interface {
+	predict(p *Proc) Tftick
+	observe(p *Proc)
+}
+
+func newRuntimePredictor(pt PredictorType) RuntimePredictor {
+	switch pt {
+	case PRED_EWMA:
+		return newEWMAPredictor()
+	case PRED_SKETCH:
+		return newQuantilePredictor()
+	default:
+		return &OraclePredictor{}
+	}
+}
+
+func predBucket(p *Proc) int {
+	switch PRED_BUCKET_BY {
+	case BUCKET_MEM:
+		return int(p.maxMem() / PRED_MEM_BUCKET_SIZE)
+	default:
+		return mapDollarsToPriority(p.willingToSpend())
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ORACLE
+// ------------------------------------------------------------------------------------------------
+
+type OraclePredictor struct{}
+
+func (op *OraclePredictor) predict(p *Proc) Tftick {
+	return p.procInternals.compGuess
+}
+
+func (op *OraclePredictor) observe(p *Proc) {}
+
+// ------------------------------------------------------------------------------------------------
+// EWMA
+// ------------------------------------------------------------------------------------------------
+
+type EWMAPredictor struct {
+	est map[int]Tftick
+}
+
+func newEWMAPredictor() *EWMAPredictor {
+	return &EWMAPredictor{est: make(map[int]Tftick)}
+}
+
+func (ep *EWMAPredictor) predict(p *Proc) Tftick {
+	if est, ok := ep.est[predBucket(p)]; ok {
+		return est
+	}
+	// haven't seen anything like this yet, fall back to what we know about the load overall
+	return Tftick(AVG_COMP)
+}
+
+func (ep *EWMAPredictor) observe(p *Proc) {
+	bucket := predBucket(p)
+	actual := p.procInternals.actualComp
+
+	est, ok := ep.est[bucket]
+	if !ok {
+		ep.est[bucket] = actual
+		return
+	}
+	ep.est[bucket] = Tftick(PRED_EWMA_ALPHA)*actual + Tftick(1-PRED_EWMA_ALPHA)*est
+}
+
+// ------------------------------------------------------------------------------------------------
+// QUANTILE
+// ------------------------------------------------------------------------------------------------
+
+// keeps a ring buffer of the last PRED_SKETCH_SIZE completions per bucket, and predicts the
+// PRED_QUANTILE'th quantile of those. The same samples are also kept sorted, so that predicting
+// (which happens for every proc that arrives) is just a lookup
+type QuantilePredictor struct {
+	samples map[int][]Tftick
+	sorted  map[int][]Tftick
+	nextInd map[int]int
+}
+
+func newQuantilePredictor() *QuantilePredictor {
+	return &QuantilePredictor{
+		samples: make(map[int][]Tftick),
+		sorted:  make(map[int][]Tftick),
+		nextInd: make(map[int]int),
+	}
+}
+
+func (qp *QuantilePredictor) predict(p *Proc) Tftick {
+	sorted := qp.sorted[predBucket(p)]
+	if len(sorted) == 0 {
+		return Tftick(AVG_COMP)
+	}
+
+	return sorted[int(PRED_QUANTILE*float64(len(sorted)-1))]
+}
+
+func (qp *QuantilePredictor) observe(p *Proc) {
+	bucket := predBucket(p)
+	actual := p.procInternals.actualComp
+
+	sorted := qp.sorted[bucket]
+
+	if len(qp.samples[bucket]) < PRED_SKETCH_SIZE {
+		qp.samples[bucket] = append(qp.samples[bucket], actual)
+	} else {
+		// the oldest sample falls out of the window
+		oldest := qp.samples[bucket][qp.nextInd[bucket]]
+		i := sort.Search(len(sorted), func(i int) bool { return sorted[i] >= oldest })
+		sorted = append(sorted[:i], sorted[i+1:]...)
+
+		qp.samples[bucket][qp.nextInd[bucket]] = actual
+		qp.nextInd[bucket] = (qp.nextInd[bucket] + 1) % PRED_SKETCH_SIZE
+	}
+
+	i := sort.Search(len(sorted), func(i int) bool { return sorted[i] >= actual })
+	sorted = append(sorted, 0)
+	copy(sorted[i+1:], sorted[i:])
+	sorted[i] = actual
+	qp.sorted[bucket] = sorted
+}
@@ -0,0 +1,42 @@
+package slasched
+
+import (
+	"testing"
+)
+
+const PRED_TEST_RUNTIME = 3.5
+
+func testPredictorConverges(t *testing.T, pred RuntimePredictor) {
+	for i := 0; i < 2*PRED_SKETCH_SIZE; i++ {
+		p := newProvProc(Tid(i), 0, newPrivProc(PRED_TEST_RUNTIME, 1, 1, 1000))
+		pred.observe(p)
+	}
+
+	p := newProvProc(-1, 0, newPrivProc(PRED_TEST_RUNTIME, 1, 1, 1000))
+	guess := pred.predict(p)
+	if guess < 0.99*PRED_TEST_RUNTIME || guess > 1.01*PRED_TEST_RUNTIME {
+		t.Fatalf("predicted %v for procs that always take %v", guess, PRED_TEST_RUNTIME)
+	}
+}
+
+func TestEWMAPredictorConverges(t *testing.T) {
+	testPredictorConverges(t, newEWMAPredictor())
+}
+
+func TestQuantilePredictorConverges(t *testing.T) {
+	testPredictorConverges(t, newQuantilePredictor())
+}
+
+// the quantile has to track the window: once it's full of the new runtime the old one is forgotten
+func TestQuantilePredictorWindow(t *testing.T) {
+	qp := newQuantilePredictor()
+	for i := 0; i < PRED_SKETCH_SIZE; i++ {
+		qp.observe(newProvProc(Tid(i), 0, newPrivProc(100, 1, 1, 1000)))
+	}
+	testPredictorConverges(t, qp)
+
+	bucket := predBucket(newProvProc(-1, 0, newPrivProc(1, 1, 1, 1000)))
+	if len(qp.sorted[bucket]) != PRED_SKETCH_SIZE {
+		t.Fatalf("window has %v samples, want %v", len(qp.sorted[bucket]), PRED_SKETCH_SIZE)
+	}
+}
@@ -15,6 +15,22 @@ type Proc struct {
 	timePlaced    Tftick
 	timeDone      Tftick
 	compDone      Tftick
+	compPred      Tftick
+	nextIO        int    // index of the next of the proc's I/O phases
+	blockedUntil  Tftick // the proc holds on to its memory but can't run until then
+	gsId          Tid    // the GS that placed the proc, for LBs that have several
+	hasRun        bool   // whether lastMachine and lastCore mean anything yet
+	lastMachine   Tid
+	lastCore      int
+	instanceMem   Tmem    // memory of the instance the proc runs in, see warm.go
+	market        *Market // only in MARKET_MODE
+	outbid        bool    // whether its bid was under the spot price as of the last market tick
+	paid          float64
+	swapped       bool   // evicted with KILL_SUSPEND and not swapped back in yet, see kill.go
+	restoreCost   Tftick // evicted with KILL_CHECKPOINT, and has to restore when it is placed next
+	migrating     bool   // on its way to another machine, by work stealing or defrag, which isn't a new placement
+	critPathLeft  Tftick // only in a DAG: the LB's guess of the critical path after the proc, and of the whole DAG's, see dag.go
+	dagCritPath   Tftick
 	procInternals *ProcInternals
 }
 
@@ -43,14 +59,109 @@ func (p *Proc) willingToSpend() float32 {
 	return p.procInternals.willingToSpend
 }
 
+// the LB's estimate of how much compute the proc needs, see predictor.go
+func (p *Proc) compGuess() Tftick {
+	if p.compPred == 0 {
+		return p.procInternals.compGuess
+	}
+	return p.compPred
+}
+
+// guessed work left on the longest path through the proc's DAG once the proc itself is done; 0 if
+// it isn't part of a DAG
+func (p *Proc) critPathAfter() Tftick {
+	return p.critPathLeft
+}
+
 func (p *Proc) maxMem() Tmem {
-	return p.procInternals.maxMem
+	return p.procInternals.maxMem + p.instanceMem
+}
+
+func (p *Proc) bid() float32 {
+	return p.procInternals.bid
+}
+
+func (p *Proc) funcId() int {
+	return p.procInternals.funcId
+}
+
+// how much memory the proc is actually using right now, given how far along it is
+func (p *Proc) memUsed() Tmem {
+	if p.swapped {
+		return 0
+	}
+	for _, phase := range p.procInternals.memPhases {
+		if p.compDone < phase.untilComp {
+			return phase.mem + p.instanceMem
+		}
+	}
+	return p.maxMem()
+}
+
+// how much memory the machines count against the proc when deciding what fits
+func (p *Proc) memCharged() Tmem {
+	if p.swapped {
+		return 0
+	}
+	if MEM_ACCOUNTING == MEM_ACTUAL {
+		return p.memUsed()
+	}
+	return p.maxMem()
 }
 
+func (p *Proc) runnable(now Tftick) bool {
+	return p.blockedUntil <= now && !p.swapped && (p.market == nil || !p.market.outbid(p))
+}
+
+// throws away all progress, eg because the proc was killed
+func (p *Proc) restart() {
+	p.compDone = 0
+	p.nextIO = 0
+	p.blockedUntil = 0
+	p.hasRun = false
+	p.swapped = false
+	p.restoreCost = 0
+}
+
+// if the proc just got to the start of an I/O phase, it blocks until now + the phase's duration
+func (p *Proc) blockIfAtIO(now Tftick) bool {
+	if p.nextIO >= len(p.procInternals.ioPhases) {
+		return false
+	}
+
+	io := p.procInternals.ioPhases[p.nextIO]
+	if p.compDone < io.atComp {
+		return false
+	}
+
+	p.blockedUntil = now + io.duration
+	p.nextIO += 1
+	return true
+}
+
+// like runTillOutOrDone, but toRun and the returned ticks are wall time on a core that does speed
+// ticks of compute per tick
+func (p *Proc) runAtSpeed(toRun Tftick, speed float64) (Tftick, bool) {
+	compUsed, done := p.runTillOutOrDone(toRun * Tftick(speed))
+	if p.market != nil {
+		p.market.charge(p, compUsed)
+	}
+	return compUsed / Tftick(speed), done
+}
+
+// runs the proc until it either used up toRun, is done, or has to go do I/O (see blockIfAtIO)
 func (p *Proc) runTillOutOrDone(toRun Tftick) (Tftick, bool) {
 
 	workLeft := p.procInternals.actualComp - p.compDone
 
+	if p.nextIO < len(p.procInternals.ioPhases) {
+		workTillIO := p.procInternals.ioPhases[p.nextIO].atComp - p.compDone
+		if workTillIO < workLeft && workTillIO <= toRun {
+			p.compDone += workTillIO
+			return workTillIO, false
+		}
+	}
+
 	if workLeft <= toRun {
 		p.compDone = p.procInternals.actualComp
 		return workLeft, true
@@ -70,9 +181,28 @@ type ProcInternals struct {
 	compGuess      Tftick
 	willingToSpend float32
 	maxMem         Tmem
+	memPhases      []MemPhase // if empty the proc uses maxMem for its whole life
+	ioPhases       []IOPhase  // ordered by atComp
+	slaSlowdown    float32    // see sla.go; only one of these is set
+	slaDeadline    Tftick
+	funcId         int     // see warm.go; only meaningful if N_FUNCTIONS > 0
+	valuation      float32 // see market.go; per unit of compute, only set in MARKET_MODE
+	bid            float32
+
+	// only set if the proc is part of a DAG
+	dag            *DAG
+	children       []*ProcInternals
+	nParents       int
+	actualCritPath Tftick // going off of the real compute; the LBs each guess their own, see dag.go
+	reservedId     Tid
 }
 
 func newPrivProc(actualComp float32, compGuess float32, willingToSpend float32, maxMem int) *ProcInternals {
 
-	return &ProcInternals{Tftick(actualComp), Tftick(compGuess), willingToSpend, Tmem(maxMem)}
+	return &ProcInternals{
+		actualComp:     Tftick(actualComp),
+		compGuess:      Tftick(compGuess),
+		willingToSpend: willingToSpend,
+		maxMem:         Tmem(maxMem),
+	}
 }
@@ -7,7 +7,8 @@ import (
 
 // note: currently we are keeping queues ordered (by expected finishing "time")
 type Queue struct {
-	q []*Proc
+	q    []*Proc
+	less func(a, b *Proc) bool // if set, the queue is ordered by this instead
 }
 
 func newQueue() *Queue {
@@ -15,6 +16,11 @@ func newQueue() *Queue {
 	return q
 }
 
+func newOrderedQueue(less func(a, b *Proc) bool) *Queue {
+	q := &Queue{q: make([]*Proc, 0), less: less}
+	return q
+}
+
 func (q *Queue) String() string {
 	str := ""
 	for _, p := range q.q {
@@ -64,10 +70,23 @@ func (q *Queue) enq(p *Proc) {
 		return
 	}
 
+	if q.less != nil {
+		for index, currProc := range q.q {
+			if q.less(p, currProc) {
+				q.q = append(q.q[:index+1], q.q[index:]...)
+				q.q[index] = p
+				return
+			}
+		}
+		q.q = append(q.q, p)
+		return
+	}
+
 	for index, currProc := range q.q {
 		if p.willingToSpend() > currProc.willingToSpend() ||
 			((currProc.willingToSpend() == p.willingToSpend()) && p.timePlaced < currProc.timePlaced) ||
-			((currProc.willingToSpend() == p.willingToSpend()) && (currProc.timePlaced == p.timePlaced) && p.compDone > currProc.compDone) {
+			((currProc.willingToSpend() == p.willingToSpend()) && (currProc.timePlaced == p.timePlaced) && p.critPathAfter() > currProc.critPathAfter()) ||
+			((currProc.willingToSpend() == p.willingToSpend()) && (currProc.timePlaced == p.timePlaced) && (p.critPathAfter() == currProc.critPathAfter()) && p.compDone > currProc.compDone) {
 			q.q = append(q.q[:index+1], q.q[index:]...)
 			q.q[index] = p
 			return
@@ -103,23 +122,32 @@ func (q *Queue) qlen() int {
 
 // }
 
-func (q *Queue) checkKill(newProc *Proc) (Tid, float32) {
+// the procs to kill to fit newProc in next to memFree, and how long it takes newProc to make up for
+// the work thrown away, going by how much more it pays than the priciest of them
+func (q *Queue) checkKill(newProc *Proc, memFree Tmem) ([]*Proc, float32) {
+
+	victims, lost := pickVictims(q.q, newProc, newProc.maxMem()-memFree+1)
+	return victims, timeToProfit(newProc, victims, lost)
+
+}
 
-	minTimeToProfit := float32(math.MaxFloat32)
-	procId := Tid(-1)
+// takes out all the procs that can't run right now
+func (q *Queue) removeBlocked(now Tftick) []*Proc {
+
+	runnable := make([]*Proc, 0, len(q.q))
+	blocked := make([]*Proc, 0)
 
 	for _, p := range q.q {
-		if (p.maxMem() > newProc.maxMem()) && (p.willingToSpend() < newProc.willingToSpend()) {
-			timeToProfit := float32(float32(p.compDone)*p.willingToSpend()) / (newProc.willingToSpend() - p.willingToSpend())
-			if timeToProfit < minTimeToProfit {
-				procId = p.procId
-				minTimeToProfit = timeToProfit
-			}
+		if p.runnable(now) {
+			runnable = append(runnable, p)
+		} else {
+			blocked = append(blocked, p)
 		}
 	}
 
-	return procId, minTimeToProfit
+	q.q = runnable
 
+	return blocked
 }
 
 func (q *Queue) kill(pid Tid) *Proc {
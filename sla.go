@@ -0,0 +1,76 @@
+package slasched
+
+import (
+	"fmt"
+)
+
+type SLAType int
+
+const (
+	SLA_SLOWDOWN SLAType = iota // latency has to stay within a factor of the proc's compute
+	SLA_DEADLINE                // latency has to stay within a fixed bound
+)
+
+// how long the proc may take from when it started, if it needs comp ticks of compute
+func (pi *ProcInternals) slaLatency(comp Tftick) Tftick {
+	if pi.slaDeadline > 0 {
+		return pi.slaDeadline
+	}
+	return Tftick(pi.slaSlowdown) * comp
+}
+
+// the latest the proc can finish and still meet its SLA
+func (p *Proc) deadline() Tftick {
+	pi := p.procInternals
+	if pi.dag == nil {
+		return p.deadlineGiven(pi.actualComp, 0, 0)
+	}
+	return p.deadlineGiven(pi.actualComp, pi.actualCritPath-pi.actualComp, pi.dag.actualCritPath())
+}
+
+// the provider's idea of the deadline, ie going off of the guess of the compute rather than the real thing
+func (p *Proc) deadlineGuess() Tftick {
+	return p.deadlineGiven(p.compGuess(), p.critPathAfter(), p.dagCritPath)
+}
+
+// the one definition of the deadline, both the real and the guessed one go through here: a proc on
+// its own gets its SLA latency for comp, a proc in a DAG gets the part of the DAG's SLA that it is
+// responsible for along the critical path (the DAG as a whole being dagCritPath long, and after it
+// there being critPathAfter left)
+func (p *Proc) deadlineGiven(comp Tftick, critPathAfter Tftick, dagCritPath Tftick) Tftick {
+	if dag := p.procInternals.dag; dag != nil {
+		dagLatency := p.procInternals.slaLatency(dagCritPath)
+		return dag.timeStarted + dagLatency*(dagCritPath-critPathAfter)/dagCritPath
+	}
+	return p.timeStarted + p.procInternals.slaLatency(comp)
+}
+
+func (ctx *LBCtx) recordSLA(p *Proc) {
+
+	lateness := p.timeDone - p.deadline()
+	met := lateness <= 0
+	if met {
+		lateness = 0
+		ctx.stats.inc(priceStat("sla_met", p.willingToSpend()), 1)
+	} else {
+		ctx.stats.inc(priceStat("sla_missed", p.willingToSpend()), 1)
+		ctx.stats.inc(priceStat("sla_lateness", p.willingToSpend()), float64(lateness))
+	}
+
+	toWrite := fmt.Sprintf("%v, %v, %v, %v, %v, %v, %v \n", ctx.nGenPerTick, ctx.lbName, p.willingToSpend(), (p.timeDone - p.timeStarted).String(), (p.deadline() - p.timeStarted).String(), met, lateness.String())
+	logWrite(SLA, toWrite)
+}
+
+// violation rates aren't counters so they only get worked out at the end
+func (ctx *LBCtx) writeSLARates() {
+	for prio := 0; prio < N_PRIORITIES; prio++ {
+		price := mapPriorityToDollars(prio)
+		met := ctx.stats.get(priceStat("sla_met", price))
+		missed := ctx.stats.get(priceStat("sla_missed", price))
+		if met+missed == 0 {
+			continue
+		}
+		toWrite := fmt.Sprintf("%v, %v, %v, %.3f\n", ctx.nGenPerTick, ctx.lbName, priceStat("sla_violation_rate", price), missed/(met+missed))
+		logWrite(LB_STATS, toWrite)
+	}
+}
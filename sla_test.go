@@ -0,0 +1,58 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// with a perfect guess the provider's deadline and the one the SLA gets checked against are the same,
+// both for procs on their own and for procs in a DAG
+func TestDeadlineGuessMatchesDeadline(t *testing.T) {
+	currTick := Tftick(5)
+	ctx := newLBCtx("test", 0, &currTick, 8)
+
+	single := newPrivProc(2, 2, 1, 1000)
+	single.slaSlowdown = 3
+
+	root := newPrivProc(1, 1, 1, 1000)
+	child := newPrivProc(4, 4, 1, 1000)
+	root.slaSlowdown, child.slaSlowdown = 3, 3
+	newTestDAG(5, []*ProcInternals{root, child}, [][2]int{{0, 1}})
+
+	for _, pi := range []*ProcInternals{single, root, child} {
+		p := newProvProc(0, 5, pi)
+		ctx.procArrived(p)
+		if p.deadline() != p.deadlineGuess() {
+			t.Fatalf("deadline %v but deadline guess %v", p.deadline(), p.deadlineGuess())
+		}
+	}
+
+	// the DAG as a whole gets 3*5 ticks, the root is responsible for the first fifth of that
+	if dl := newProvProc(0, 5, root).deadline(); dl != 5+3 {
+		t.Fatalf("root of the DAG has deadline %v, want %v", dl, 5+3)
+	}
+}
+
+// the LB guesses a DAG's critical path with its own predictor, not with the load generator's guess:
+// a predictor that hasn't seen anything yet guesses AVG_COMP for both nodes
+func TestDAGDeadlineGuessFromPredictor(t *testing.T) {
+	currTick := Tftick(5)
+	ctx := newLBCtx("test", 0, &currTick, 8)
+	ctx.predictor = newEWMAPredictor()
+
+	root := newPrivProc(1, 1, 1, 1000)
+	child := newPrivProc(4, 4, 1, 1000)
+	root.slaSlowdown, child.slaSlowdown = 3, 3
+	newTestDAG(5, []*ProcInternals{root, child}, [][2]int{{0, 1}})
+
+	p := newProvProc(0, 5, root)
+	ctx.procArrived(p)
+
+	// the DAG is guessed to be 2*AVG_COMP long, half of it after the root
+	want := 5 + 3*2*Tftick(AVG_COMP)/2
+	if dl := p.deadlineGuess(); dl != want {
+		t.Fatalf("root of the DAG has a guessed deadline of %v, want %v", dl, want)
+	}
+	if p.deadline() != 5+3 {
+		t.Fatalf("root of the DAG has deadline %v, want %v", p.deadline(), 5+3)
+	}
+}
@@ -0,0 +1,106 @@
+package slasched
+
+// sparrow (Ousterhout et al, SOSP '13): schedulers don't pick a machine for a proc, they put
+// reservations on SPARROW_PROBE_RATIO times as many random machines as they have procs, and the
+// machines ask for a proc when they have a core free (late binding)
+type SparrowLB struct {
+	currTickPtr   *Tftick
+	ctx           *LBCtx
+	machines      []*SparrowMachine
+	scheds        []*SparrowSched
+	roundRobinInd int
+}
+
+func newSparrowLB(fleet FleetSpec, nGenPerTick int, nScheds int, currTickPtr *Tftick, ctx *LBCtx) *SparrowLB {
+
+	slb := &SparrowLB{
+		currTickPtr:   currTickPtr,
+		ctx:           ctx,
+		machines:      make([]*SparrowMachine, 0),
+		scheds:        make([]*SparrowSched, nScheds),
+		roundRobinInd: 0,
+	}
+
+	ctx.enqProc = slb.enqProc
+
+	for i, mc := range fleet.machineClasses() {
+		slb.machines = append(slb.machines, newSparrowMachine(Tid(i), mc.numCores, mc.mem, mc.speed, currTickPtr, nGenPerTick, ctx))
+	}
+
+	// every scheduler can put reservations on every machine
+	for i := 0; i < nScheds; i++ {
+		slb.scheds[i] = newSparrowSched(Tid(i), slb.machines, currTickPtr, ctx)
+	}
+
+	return slb
+}
+
+func (slb *SparrowLB) getCtx() *LBCtx {
+	return slb.ctx
+}
+
+func (slb *SparrowLB) enqProc(p *Proc) {
+
+	slb.scheds[slb.roundRobinInd].procQ = append(slb.scheds[slb.roundRobinInd].procQ, p)
+	slb.roundRobinInd += 1
+	if slb.roundRobinInd >= len(slb.scheds) {
+		slb.roundRobinInd = 0
+	}
+
+}
+
+func (slb *SparrowLB) placeProcs() {
+
+	slb.ctx.net.deliver(*slb.currTickPtr)
+
+	for _, s := range slb.scheds {
+		s.placeProcs()
+	}
+
+	// this is where procs actually get placed
+	for _, m := range slb.machines {
+		if !m.failed {
+			m.requestProcs()
+		}
+	}
+}
+
+func (slb *SparrowLB) tick() {
+
+	mids := make([]Tid, len(slb.machines))
+	for i, m := range slb.machines {
+		mids[i] = m.machineId
+	}
+
+	failed, recovered := slb.ctx.failures.tick(*slb.currTickPtr, mids)
+	for _, mid := range recovered {
+		slb.machines[mid].failed = false
+		slb.ctx.machineRecovered(mid)
+	}
+	for _, mid := range failed {
+		slb.failMachine(mid)
+	}
+
+	for _, m := range slb.machines {
+		if !m.failed {
+			m.tick()
+		}
+	}
+}
+
+// a failed machine's reservations are gone too (the schedulers time them out)
+func (slb *SparrowLB) failMachine(mid Tid) {
+	slb.ctx.machineFailed()
+
+	m := slb.machines[mid]
+	m.failed = true
+	for _, res := range m.reservations {
+		res.sched.reservationGone(res.batch)
+	}
+	m.reservations = make([]*Reservation, 0)
+
+	for _, p := range m.fail() {
+		slb.ctx.procLost(p)
+		slb.scheds[p.gsId].procQ = append(slb.scheds[p.gsId].procQ, p)
+	}
+}
@@ -0,0 +1,37 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// once a proc binds to one of its reservations, the scheduler cancels the others, so the machines
+// holding them never ask for it
+func TestSparrowCancelsExtraReservations(t *testing.T) {
+	currTick := Tftick(1)
+	fleet := uniformFleet(4, 1)
+	ctx := newLBCtx("test", 0, &currTick, fleet.totalCompute())
+	slb := newSparrowLB(fleet, 0, 1, &currTick, ctx)
+
+	p := newTestProc(0, 1, 1, 100)
+	ctx.procArrived(p)
+	slb.enqProc(p)
+	slb.placeProcs()
+
+	nRunning := 0
+	for _, m := range slb.machines {
+		nRunning += len(m.procQ)
+		if len(m.reservations) > 0 {
+			t.Fatalf("machine %v still holds %v reservations", m.machineId, len(m.reservations))
+		}
+	}
+	if nRunning != 1 {
+		t.Fatalf("%v copies of the proc are running, want 1", nRunning)
+	}
+
+	if cancelled := ctx.stats.get("sparrow_cancelled"); cancelled != SPARROW_PROBE_RATIO-1 {
+		t.Fatalf("%v reservations cancelled, want %v", cancelled, SPARROW_PROBE_RATIO-1)
+	}
+	if noops := ctx.stats.get("sparrow_noop_replies"); noops != 0 {
+		t.Fatalf("%v machines asked for a proc that was already launched", noops)
+	}
+}
@@ -0,0 +1,81 @@
+package slasched
+
+type Reservation struct {
+	sched     *SparrowSched
+	batch     *SparrowBatch
+	machine   *SparrowMachine
+	answered  bool // the machine asked for its proc
+	cancelled bool
+}
+
+// runs procs just like a hermod machine, one per core, but fills its cores itself from its
+// reservations
+type SparrowMachine struct {
+	*HermodMachine
+	reservations []*Reservation // fifo
+	nRequesting  int            // cores we asked a scheduler to fill and haven't heard back about
+	failed       bool
+}
+
+func newSparrowMachine(mid Tid, numCores int, totMem Tmem, speed float64, currTickPtr *Tftick, worldNumProcsGenPerTick int, ctx *LBCtx) *SparrowMachine {
+	return &SparrowMachine{
+		HermodMachine: newHermodMachine(mid, numCores, totMem, speed, currTickPtr, worldNumProcsGenPerTick, ctx, LOCAL_PS),
+		reservations:  make([]*Reservation, 0),
+	}
+}
+
+func (sm *SparrowMachine) reserve(res *Reservation) {
+	if sm.failed {
+		res.sched.reservationGone(res.batch)
+		return
+	}
+	sm.reservations = append(sm.reservations, res)
+}
+
+func (sm *SparrowMachine) cancel(res *Reservation) {
+	res.cancelled = true
+	for i, r := range sm.reservations {
+		if r == res {
+			sm.reservations = append(sm.reservations[:i], sm.reservations[i+1:]...)
+			sm.ctx.stats.inc("sparrow_cancelled", 1)
+			return
+		}
+	}
+}
+
+func (sm *SparrowMachine) coresFree() int {
+	return sm.numCores - len(sm.procQ) - sm.nRequesting
+}
+
+// late binding: for every free core, ask the scheduler of the reservation at the front of the
+// queue for a proc
+func (sm *SparrowMachine) requestProcs() {
+	for sm.coresFree() > 0 && len(sm.reservations) > 0 {
+		res := sm.reservations[0]
+		sm.reservations = sm.reservations[1:]
+		sm.nRequesting += 1
+
+		// if the request is lost the machine times out waiting, and the scheduler times out the reservation
+		sm.ctx.net.send(MSG_GET_TASK, 0, func() { res.sched.getProc(res) }, func() {
+			sm.nRequesting -= 1
+			res.sched.reservationGone(res.batch)
+		})
+	}
+}
+
+// the scheduler's answer to a request: a proc, or nil if the batch was already all launched. A proc
+// that doesn't fit in memory anymore, or that gets here after the machine failed, goes back
+func (sm *SparrowMachine) procArrived(res *Reservation, p *Proc) {
+	sm.nRequesting -= 1
+	if p == nil {
+		return
+	}
+
+	if sm.failed || p.maxMem() >= sm.memFree() {
+		sm.ctx.stats.inc("sparrow_bounced", 1)
+		res.sched.procQ = append(res.sched.procQ, p)
+		return
+	}
+
+	sm.placeProc(p)
+}
@@ -0,0 +1,130 @@
+package slasched
+
+import "fmt"
+
+// the procs a scheduler places together, with the reservations it has out for them. Sparrow samples
+// per job; our procs don't come in jobs, so a batch is whatever arrived at the scheduler in a tick
+type SparrowBatch struct {
+	procs        []*Proc
+	nLaunched    int
+	reservations []*Reservation
+	outstanding  int // reservations the scheduler hasn't heard back about
+}
+
+func (b *SparrowBatch) allLaunched() bool {
+	return b.nLaunched == len(b.procs)
+}
+
+type SparrowSched struct {
+	schedId     Tid
+	machines    []*SparrowMachine
+	procQ       []*Proc
+	currTickPtr *Tftick
+	ctx         *LBCtx
+}
+
+func newSparrowSched(id Tid, machines []*SparrowMachine, currTickPtr *Tftick, ctx *LBCtx) *SparrowSched {
+	return &SparrowSched{
+		schedId:     id,
+		machines:    machines,
+		procQ:       make([]*Proc, 0),
+		currTickPtr: currTickPtr,
+		ctx:         ctx,
+	}
+}
+
+// batch sampling: put SPARROW_PROBE_RATIO reservations per proc out on random machines, on as many
+// different machines as there are
+func (s *SparrowSched) placeProcs() {
+
+	if len(s.procQ) == 0 {
+		return
+	}
+
+	live := make([]*SparrowMachine, 0, len(s.machines))
+	for _, m := range s.machines {
+		if !m.failed {
+			live = append(live, m)
+		}
+	}
+	if len(live) == 0 {
+		return
+	}
+
+	batch := &SparrowBatch{procs: s.procQ}
+	s.procQ = make([]*Proc, 0)
+
+	nRes := SPARROW_PROBE_RATIO * len(batch.procs)
+	for len(batch.reservations) < nRes {
+		for _, m := range pickRandomElements(live, nRes-len(batch.reservations)) {
+			batch.reservations = append(batch.reservations, &Reservation{sched: s, batch: batch, machine: m})
+		}
+	}
+	batch.outstanding = len(batch.reservations)
+
+	toWrite := fmt.Sprintf("%v, sparrow sched %v reserving %v machines for %v procs \n", int(*s.currTickPtr), s.schedId, nRes, len(batch.procs))
+	logWrite(s.ctx.logs.sched, toWrite)
+
+	for _, res := range batch.reservations {
+		res := res
+		s.ctx.net.send(MSG_RESERVE, 0, func() { res.machine.reserve(res) }, func() { s.reservationGone(batch) })
+	}
+}
+
+// a machine with a core free asks for a proc: it gets the next one in the batch that hasn't been
+// launched, or nothing if they all have
+func (s *SparrowSched) getProc(res *Reservation) {
+
+	res.answered = true
+	batch := res.batch
+	batch.outstanding -= 1
+
+	if batch.allLaunched() {
+		s.ctx.stats.inc("sparrow_noop_replies", 1)
+		s.ctx.net.send(MSG_TASK, 0, func() { res.machine.procArrived(res, nil) }, func() { res.machine.nRequesting -= 1 })
+		return
+	}
+
+	p := batch.procs[batch.nLaunched]
+	batch.nLaunched += 1
+	p.gsId = s.schedId
+
+	// if the proc is lost on the way, the machine stops waiting for it and the scheduler sends it out again
+	s.ctx.net.send(MSG_TASK, 0, func() { res.machine.procArrived(res, p) }, func() {
+		res.machine.nRequesting -= 1
+		s.procQ = append(s.procQ, p)
+	})
+
+	if batch.allLaunched() {
+		s.cancel(batch)
+	}
+	s.settle(batch)
+}
+
+// tell the machines that haven't asked yet that they don't need to
+func (s *SparrowSched) cancel(batch *SparrowBatch) {
+	for _, res := range batch.reservations {
+		if res.answered || res.cancelled {
+			continue
+		}
+		res := res
+		s.ctx.net.send(MSG_CANCEL, 0, func() { res.machine.cancel(res) }, nil)
+	}
+}
+
+// a reservation that the scheduler won't hear back about (lost, or its machine failed)
+func (s *SparrowSched) reservationGone(batch *SparrowBatch) {
+	batch.outstanding -= 1
+	s.settle(batch)
+}
+
+// if none of the batch's reservations are left and some of its procs still haven't launched, they
+// get sampled again next tick
+func (s *SparrowSched) settle(batch *SparrowBatch) {
+	if batch.outstanding > 0 || batch.allLaunched() {
+		return
+	}
+	s.ctx.stats.inc("sparrow_resampled", float64(len(batch.procs)-batch.nLaunched))
+	s.procQ = append(s.procQ, batch.procs[batch.nLaunched:]...)
+	batch.nLaunched = len(batch.procs)
+}
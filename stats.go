@@ -0,0 +1,63 @@
+package slasched
+
+import (
+	"fmt"
+	"sort"
+)
+
+// named counters for things we want to compare across LBs that don't fit the per-proc or per-tick
+// logs; dumped to LB_STATS at the end of a run
+type LBStats struct {
+	counters map[string]float64
+}
+
+func newLBStats() *LBStats {
+	return &LBStats{counters: make(map[string]float64)}
+}
+
+func (s *LBStats) inc(name string, amt float64) {
+	s.counters[name] += amt
+}
+
+func (s *LBStats) get(name string) float64 {
+	return s.counters[name]
+}
+
+func priceStat(name string, price float32) string {
+	return fmt.Sprintf("%v[%.2f]", name, price)
+}
+
+func (s *LBStats) write(nGenPerTick int, lbName string) {
+
+	names := make([]string, 0, len(s.counters))
+	for name := range s.counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		toWrite := fmt.Sprintf("%v, %v, %v, %.3f\n", nGenPerTick, lbName, name, s.counters[name])
+		logWrite(LB_STATS, toWrite)
+	}
+}
+
+func (ctx *LBCtx) writeTailAndUtil() {
+
+	write := func(name string, val float64) {
+		toWrite := fmt.Sprintf("%v, %v, %v, %.3f\n", ctx.nGenPerTick, ctx.lbName, name, val)
+		logWrite(LB_STATS, toWrite)
+	}
+
+	if len(ctx.latencies) > 0 {
+		sorted := make([]float64, len(ctx.latencies))
+		copy(sorted, ctx.latencies)
+		sort.Float64s(sorted)
+		for _, q := range []float64{0.5, 0.99, 0.999} {
+			write(fmt.Sprintf("latency_p%v", q*100), sorted[int(q*float64(len(sorted)-1))])
+		}
+	}
+
+	if cores := ctx.stats.get("core_ticks"); cores > 0 {
+		write("utilization", 1-ctx.stats.get("idle_core_ticks")/cores)
+	}
+}
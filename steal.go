@@ -0,0 +1,74 @@
+package slasched
+
+// the procs at the back of the machine's queue that haven't started yet, so moving them only means
+// moving the proc, not any state
+func (sd *Machine) stealable(n int) []*Proc {
+	procs := make([]*Proc, 0, n)
+	q := sd.activeQ.getQ()
+	for i := len(q) - 1; i >= 0 && len(procs) < n; i-- {
+		if q[i].compDone == 0 && q[i].runnable(*sd.currTickPtr) {
+			procs = append(procs, q[i])
+		}
+	}
+	return procs
+}
+
+// machines with idle cores look at STEAL_K others, and take waiting procs from the one with the
+// most of them. The procs go to the thief like any other placement, from the GS that first placed
+// them, and can't run until STEAL_COST has passed; they don't count as placed again though
+func (mlb *MineLB) stealWork() {
+
+	live := make([]*Machine, 0, len(mlb.machines))
+	for _, m := range mlb.machines {
+		if !m.failed && !m.draining {
+			live = append(live, m)
+		}
+	}
+
+	for _, thief := range live {
+		idleCores := thief.numCores - thief.activeQ.qlen()
+		if idleCores <= 0 {
+			continue
+		}
+
+		candidates := make([]*Machine, len(live))
+		copy(candidates, live)
+
+		var victim *Machine
+		mostWaiting := 0
+		for _, m := range pickRandomElements(candidates, STEAL_K) {
+			if m == thief {
+				continue
+			}
+			waiting, answered, _ := probe(mlb.ctx.net, func() int { return m.activeQ.qlen() - m.numCores })
+			if !answered {
+				continue
+			}
+			if waiting > mostWaiting {
+				mostWaiting = waiting
+				victim = m
+			}
+		}
+		if victim == nil {
+			continue
+		}
+
+		// the thief's memory only goes down once the placements land, so keep track of what the
+		// procs already on their way there will take
+		memFree := thief.memFree()
+		for _, p := range victim.stealable(min(idleCores, mostWaiting)) {
+			if p.maxMem() >= memFree {
+				continue
+			}
+			memFree -= p.maxMem()
+			victim.activeQ.kill(p.procId)
+			p.blockedUntil = *mlb.currTickPtr + STEAL_COST
+			p.migrating = true
+
+			mlb.ctx.stats.inc("procs_stolen", 1)
+			mlb.ctx.stats.inc("steal_cost", STEAL_COST)
+
+			mlb.GSSs[p.gsId].sendPlacement(thief, p, 0)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package slasched
+
+import (
+	"testing"
+)
+
+// a stolen proc that bounces off a machine that went down in the meantime goes back to its GS, and
+// its next placement counts as a new one
+func TestStealBounceResetsStolen(t *testing.T) {
+	currTick := Tftick(0)
+	ctx := newLBCtx("test", 0, &currTick, 8)
+	mlb := newMineLB(uniformFleet(2, 4), 0, 1, &currTick, ctx)
+
+	p := newTestProc(0, 10, 1, 100)
+	p.migrating = true
+	mlb.machines[1].failed = true
+	mlb.GSSs[0].sendPlacement(mlb.machines[1], p, 0)
+
+	if p.migrating {
+		t.Fatalf("proc is still marked as migrating after bouncing")
+	}
+	if len(mlb.GSSs[0].toReq) != 1 || mlb.GSSs[0].toReq[0] != p {
+		t.Fatalf("bounced proc didn't go back to its GS, toReq is %v", mlb.GSSs[0].toReq)
+	}
+
+	mlb.GSSs[0].sendPlacement(mlb.machines[0], p, 0)
+	if placed := ctx.stats.get("procs_placed"); placed != 1 {
+		t.Fatalf("%v procs placed, want the bounced proc to count once it lands", placed)
+	}
+}
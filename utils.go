@@ -22,6 +22,24 @@ func mapPriorityToDollars(priority int) float32 {
 	return []float32{0.3, 0.7, 1.0, 1.5, 2}[priority]
 }
 
+// same as the ratio to the top price, which is what EDF used to derive its deadlines from
+func mapPriorityToSlowdown(priority int) float32 {
+	return []float32{6.67, 2.86, 2, 1.33, 1}[priority]
+}
+
+func mapPriorityToDeadline(priority int) Tftick {
+	return []Tftick{60, 30, 20, 10, 5}[priority]
+}
+
+func mapDollarsToPriority(price float32) int {
+	for prio := 0; prio < N_PRIORITIES; prio++ {
+		if mapPriorityToDollars(prio) == price {
+			return prio
+		}
+	}
+	return -1
+}
+
 func genRandPriority() int {
 
 	sample := r.Intn(100)
@@ -52,14 +70,46 @@ const (
 	IDEAL_USAGE
 	HERMOD_USAGE
 	EDF_USAGE
+	LB_STATS
+	DAGS_DONE
+	SLA
+	LB_PROCS_DONE
+	LB_SCHED
+	LB_USAGE
+	MARKET
 )
 
 func (pt PrintType) fileName() string {
-	return []string{"results/procs_done.txt", "results/ideal_procs_done.txt", "results/hermod_procs_done.txt", "results/edf_procs_done.txt", "results/sched.txt", "results/ideal_sched.txt", "results/hermod_sched.txt", "results/edf_sched.txt", "results/usage.txt", "results/ideal_usage.txt", "results/hermod_usage.txt", "results/edf_usage.txt"}[pt]
+	return []string{"results/procs_done.txt", "results/ideal_procs_done.txt", "results/hermod_procs_done.txt", "results/edf_procs_done.txt", "results/sched.txt", "results/ideal_sched.txt", "results/hermod_sched.txt", "results/edf_sched.txt", "results/usage.txt", "results/ideal_usage.txt", "results/hermod_usage.txt", "results/edf_usage.txt", "results/lb_stats.txt", "results/dags_done.txt", "results/sla.txt", "results/lb_procs_done.txt", "results/lb_sched.txt", "results/lb_usage.txt", "results/market.txt"}[pt]
 }
 
 func (pt PrintType) should_print() bool {
-	return []bool{VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_SCHED_INFO, VERBOSE_IDEAL_SCHED_INFO, VERBOSE_HERMOD_SCHED_INFO, VERBOSE_EDF_SCHED_INFO, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS}[pt]
+	return []bool{VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_SCHED_INFO, VERBOSE_IDEAL_SCHED_INFO, VERBOSE_HERMOD_SCHED_INFO, VERBOSE_EDF_SCHED_INFO, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS, VERBOSE_LB_SCHED_INFO, VERBOSE_USAGE_STATS, VERBOSE_USAGE_STATS}[pt]
+}
+
+// where an LB's machines write their per-proc, usage and scheduling logs. The first LBs each have
+// their own files; the ones added since share the LB_ ones, with the LB's name at the start of
+// every line
+type LBLogs struct {
+	procsDone PrintType
+	usage     PrintType
+	sched     PrintType
+	prefix    string
+}
+
+func lbLogs(lbName string) LBLogs {
+	switch lbName {
+	case "mine":
+		return LBLogs{PROCS_DONE, USAGE, SCHED, ""}
+	case "ideal":
+		return LBLogs{IDEAL_PROCS_DONE, IDEAL_USAGE, IDEAL_SCHED, ""}
+	case "hermod":
+		return LBLogs{HERMOD_PROCS_DONE, HERMOD_USAGE, HERMOD_SCHED, ""}
+	case "edf":
+		return LBLogs{EDF_PROCS_DONE, EDF_USAGE, EDF_SCHED, ""}
+	default:
+		return LBLogs{LB_PROCS_DONE, LB_USAGE, LB_SCHED, lbName + ", "}
+	}
 }
 
 func logWrite(printType PrintType, toWrite string) {
@@ -81,7 +131,7 @@ func logWrite(printType PrintType, toWrite string) {
 }
 
 func emptyFiles() {
-	types := []PrintType{PROCS_DONE, IDEAL_PROCS_DONE, EDF_PROCS_DONE, HERMOD_PROCS_DONE, SCHED, IDEAL_SCHED, HERMOD_SCHED, EDF_SCHED, USAGE, IDEAL_USAGE, HERMOD_USAGE, EDF_USAGE}
+	types := []PrintType{PROCS_DONE, IDEAL_PROCS_DONE, EDF_PROCS_DONE, HERMOD_PROCS_DONE, SCHED, IDEAL_SCHED, HERMOD_SCHED, EDF_SCHED, USAGE, IDEAL_USAGE, HERMOD_USAGE, EDF_USAGE, LB_STATS, DAGS_DONE, SLA, LB_PROCS_DONE, LB_SCHED, LB_USAGE, MARKET}
 
 	for _, t := range types {
 		os.Truncate(t.fileName(), 0)
@@ -160,6 +210,23 @@ func pickRandomElementsMap[K comparable, V any](inpMap map[K]V, k int) map[K]V {
 	return result
 }
 
+func Keys[M ~map[K]V, K comparable, V any](m M) []K {
+	r := make([]K, 0, len(m))
+	for k := range m {
+		r = append(r, k)
+	}
+	return r
+}
+
+func containsTid(list []Tid, value Tid) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func Values[M ~map[K]V, K comparable, V any](m M) []V {
 	r := make([]V, 0, len(m))
 	for _, v := range m {
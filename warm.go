@@ -0,0 +1,136 @@
+package slasched
+
+import "fmt"
+
+// the warm instances a machine keeps around after procs finish, so that the next proc of the same
+// function can skip the cold start. Bounded by memory: every instance takes FUNC_INSTANCE_MEM, and
+// the cache can use up to WARM_CACHE_FRAC of the machine's memory. The cache's instances are charged
+// to the machine's memory like procs are, but they get dropped before anything that is running
+// gets killed
+type WarmCache struct {
+	maxInstances int
+	instances    []int // function ids, least recently used first; a function can have several
+}
+
+func newWarmCache(machineMem Tmem) *WarmCache {
+	maxInstances := 0
+	if FUNC_INSTANCE_MEM > 0 {
+		maxInstances = int(WARM_CACHE_FRAC * float64(machineMem) / FUNC_INSTANCE_MEM)
+	}
+	return &WarmCache{
+		maxInstances: maxInstances,
+		instances:    make([]int, 0),
+	}
+}
+
+// if procs don't have functions, everything is warm
+func (wc *WarmCache) isWarm(p *Proc) bool {
+	if N_FUNCTIONS == 0 {
+		return true
+	}
+	for _, fn := range wc.instances {
+		if fn == p.funcId() {
+			return true
+		}
+	}
+	return false
+}
+
+// take a warm instance for the proc out of the cache, if there is one
+func (wc *WarmCache) take(p *Proc) bool {
+	for i, fn := range wc.instances {
+		if fn == p.funcId() {
+			wc.instances = append(wc.instances[:i], wc.instances[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// the memory a proc's instance takes; the proc carries it along for as long as it is running,
+// whether the instance was warm or cold, and then it passes to the cache
+func instanceMem() Tmem {
+	if N_FUNCTIONS == 0 {
+		return 0
+	}
+	return FUNC_INSTANCE_MEM
+}
+
+// the memory the cached instances are using
+func (wc *WarmCache) mem() Tmem {
+	return Tmem(len(wc.instances)) * instanceMem()
+}
+
+// drops the least recently used instance, if there is one
+func (wc *WarmCache) drop() bool {
+	if len(wc.instances) == 0 {
+		return false
+	}
+	wc.instances = wc.instances[1:]
+	return true
+}
+
+// drops the least recently used instances until p fits in memFree, and returns how much is free for
+// p then; the instance p would take is spared, and counts as free since it goes to p anyway
+func (wc *WarmCache) makeRoom(p *Proc, memFree Tmem) Tmem {
+	if N_FUNCTIONS == 0 {
+		return memFree
+	}
+
+	spared := -1
+	for i, fn := range wc.instances {
+		if fn == p.funcId() {
+			spared = i
+			memFree += instanceMem()
+			break
+		}
+	}
+
+	kept := make([]int, 0, len(wc.instances))
+	for i, fn := range wc.instances {
+		if i != spared && p.maxMem() >= memFree {
+			memFree += instanceMem()
+			continue
+		}
+		kept = append(kept, fn)
+	}
+	wc.instances = kept
+
+	return memFree
+}
+
+// the proc is done, its instance stays warm; evict the least recently used ones if that is too many
+func (wc *WarmCache) release(p *Proc) {
+	if N_FUNCTIONS == 0 {
+		return
+	}
+	wc.instances = append(wc.instances, p.funcId())
+	if len(wc.instances) > wc.maxInstances {
+		wc.instances = wc.instances[len(wc.instances)-wc.maxInstances:]
+	}
+}
+
+// called by the machines when they place a proc, after making room for it. A cold start means the
+// proc has to wait COLD_START_DELAY before it can run
+func (ctx *LBCtx) startInstance(p *Proc, wc *WarmCache) {
+	if N_FUNCTIONS == 0 {
+		return
+	}
+
+	if wc.take(p) {
+		ctx.stats.inc("warm_starts", 1)
+		return
+	}
+
+	ctx.stats.inc("cold_starts", 1)
+	p.blockedUntil = max(p.blockedUntil, *ctx.currTickPtr+COLD_START_DELAY)
+}
+
+func (ctx *LBCtx) writeColdStartRate() {
+	cold, warm := ctx.stats.get("cold_starts"), ctx.stats.get("warm_starts")
+	if cold+warm == 0 {
+		return
+	}
+	toWrite := fmt.Sprintf("%v, %v, %v, %.3f\n", ctx.nGenPerTick, ctx.lbName, "cold_start_rate", cold/(cold+warm))
+	logWrite(LB_STATS, toWrite)
+}
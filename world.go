@@ -14,11 +14,114 @@ const (
 	K_CHOICES_DOWN = 3
 	K_CHOICES_UP   = 3
 
+	RUNTIME_PREDICTOR    = PRED_ORACLE
+	PRED_BUCKET_BY       = BUCKET_PRICE
+	PRED_EWMA_ALPHA      = 0.1
+	PRED_QUANTILE        = 0.5
+	PRED_SKETCH_SIZE     = 256
+	PRED_MEM_BUCKET_SIZE = 1000
+
+	MEM_TRAJECTORIES   = false
+	MEM_ACCOUNTING     = MEM_RESERVED
+	MAX_MEM_PHASES     = 4
+	MIN_MEM_PHASE_FRAC = 0.2
+	OOM_VICTIM_POLICY  = OOM_KILL_LARGEST
+
+	DAG_PROB       = 0.0
+	DAG_MAX_CHAIN  = 4
+	DAG_MAX_FANOUT = 8
+
+	IO_PROB       = 0.0
+	MAX_IO_PHASES = 3
+	AVG_IO_TIME   = 1.0
+
+	SLA_TYPE = SLA_SLOWDOWN
+
+	MACHINE_MTBF = 0.0 // in ticks; 0 means machines don't fail on their own
+	MACHINE_MTTR = 10.0
+	RACK_SIZE    = 10
+	RACK_MTBF    = 0.0
+	RACK_MTTR    = 20.0
+
+	// in ticks of core time, lost every time a core switches procs
+	CTX_SWITCH_COST = 0.0
+	PREEMPT_COST    = 0.0 // on top of the context switch, if the proc switched away from wasn't done
+	MIGRATION_COST  = 0.0 // on top of the context switch, if the proc last ran on another core
+
+	COLD_START_DELAY  = 0.5
+	FUNC_INSTANCE_MEM = 200 // an instance's memory, on top of the proc's own if it was cold started
+	WARM_CACHE_FRAC   = 0.1 // how much of a machine's memory can go to keeping instances warm
+
+	// control plane messages between GSs and machines, see network.go
+	NET_DELAY   = 0.0
+	NET_JITTER  = 0.0
+	NET_LOSS    = 0.0
+	NET_TIMEOUT = 2.0 // how long a GS waits to hear back about a placement before it tries again
+
+	WORK_STEALING = false
+	STEAL_K       = 2   // how many machines an idle machine looks at to steal from
+	STEAL_COST    = 0.1 // how long a stolen proc takes to move
+
+	DEFRAG                 = false
+	DEFRAG_THRESHOLD       = 0.5    // only if at least this much of the free memory isn't in the biggest hole
+	DEFRAG_MAX_MIGRATIONS  = 8      // per tick
+	DEFRAG_CANDIDATES      = 4      // how many of the machines with the most free memory to try to make room on
+	MIGRATION_COST_PER_MEM = 0.0001 // ticks a migrated proc is paused, per unit of memory it uses
+
+	// power, in watts; energy is watts times ticks
+	IDLE_WATTS_PER_CORE = 5.0
+	BUSY_WATTS_PER_CORE = 15.0
+	WATTS_PER_MEM       = 0.001 // per unit of memory in use
+	OFF_WATTS_PER_CORE  = 0.5
+	POWER_OFF_AFTER     = 0   // ticks a machine has to be empty before it turns off; 0 means never
+	WAKE_DELAY          = 2.0 // how long a proc placed on a machine that is off waits for it
+
+	AUTOSCALE            = false
+	SCALE_SIGNAL         = SCALE_ON_UTIL
+	SCALE_UP_THRESHOLD   = 0.85 // in whatever units SCALE_SIGNAL is in
+	SCALE_DOWN_THRESHOLD = 0.4
+	SCALE_STEP           = 2
+	SCALE_COOLDOWN       = 5.0
+	BOOT_DELAY           = 3.0
+	MIN_MACHINES         = 10
+	MAX_MACHINES         = 200
+
+	// hermod packs procs onto few machines at low load, and balances them at high load. Otherwise it
+	// always balances
+	HERMOD_HYBRID     = false
+	HERMOD_LOAD_ALPHA = 0.3 // for the EWMA of the load, in procs per unit of compute
+	HERMOD_HIGH_LOAD  = 0.9 // switch to balancing above this
+	HERMOD_LOW_LOAD   = 0.6 // and back to packing below this
+
+	SPARROW_PROBE_RATIO = 2 // reservations per proc
+
+	ADMISSION_POLICY    = ADMIT_ALL
+	ADMISSION_OVERLOAD  = 2.0 // for ADMIT_BY_PRICE: ticks of backlog per unit of compute that count as overloaded
+	ADMISSION_MIN_PRICE = 1.0 // the cheapest procs that are still admitted when overloaded
+
+	KILL_SEMANTICS          = KILL_CHECKPOINT // what happens to procs evicted to make room, see kill.go
+	CHECKPOINT_SAVE_COST    = 0.0
+	CHECKPOINT_RESTORE_COST = 0.0
+	SWAP_COST_PER_MEM       = 0.0001 // ticks a suspended proc takes to swap out or back in, per unit of memory
+
+	MARKET_MODE            = false
+	MARKET_START_PRICE     = 0.5 // spot price, per unit of compute
+	MARKET_MIN_PRICE       = 0.05
+	MARKET_STEP            = 0.2 // how fast the price reacts to load
+	MARKET_TARGET_UTIL     = 0.8
+	MARKET_VALUATION_SIGMA = 0.5 // of the log of a client's valuation around its class's price
+	MARKET_BID_SHADE       = 0.9 // clients bid this much of their valuation
+
+	// for the random, round-robin, jsq and power-of-d LBs
+	BASELINE_D            = 2
+	BASELINE_LOCAL_POLICY = LOCAL_PS // or LOCAL_FIFO
+
 	VERBOSE_USAGE_STATS       = true
 	VERBOSE_SCHED_INFO        = false
 	VERBOSE_IDEAL_SCHED_INFO  = false
 	VERBOSE_HERMOD_SCHED_INFO = false
 	VERBOSE_EDF_SCHED_INFO    = false
+	VERBOSE_LB_SCHED_INFO     = false
 )
 
 const SEED = 12345
@@ -29,6 +132,7 @@ type LB interface {
 	placeProcs()
 	tick()
 	enqProc(*Proc)
+	getCtx() *LBCtx
 }
 
 type LBType int
@@ -38,14 +142,69 @@ const (
 	IDEAL
 	HERMOD
 	EDF
+	SRPT
+	SRPT_DIST
+	PRICE_SRPT
+	PRICE_SRPT_DIST
+	SPARROW
+	RANDOM
+	ROUND_ROBIN
+	JSQ
+	POWER_OF_D
+	LLF
+	EDF_DROP
+	EDF_DEMOTE
+	LLF_DROP
+	LLF_DEMOTE
+	EDF_DIST
 )
 
-func (lbt LBType) newLB(numMachines int, numCores int, nGenPerTick int, nGSSs int, currTickPtr *Tftick) LB {
-	return []LB{newMineLB(numMachines, numCores, nGenPerTick, nGSSs, currTickPtr), newIdealLB(numMachines, numCores, nGenPerTick, currTickPtr), newHermodLB(numMachines, numCores, nGenPerTick, nGSSs, currTickPtr), newEDFLB(numMachines, numCores, nGenPerTick, currTickPtr)}[lbt]
+func (lbt LBType) newLB(fleet FleetSpec, nGenPerTick int, nGSSs int, currTickPtr *Tftick) LB {
+	ctx := newLBCtx(lbt.string(), nGenPerTick, currTickPtr, fleet.totalCompute())
+	switch lbt {
+	case IDEAL:
+		return newIdealLB(fleet, nGenPerTick, currTickPtr, ctx, LOCAL_PRICE)
+	case HERMOD:
+		return newHermodLB(fleet, nGenPerTick, nGSSs, currTickPtr, ctx, LOCAL_PS)
+	case SRPT:
+		return newIdealLB(fleet, nGenPerTick, currTickPtr, ctx, LOCAL_SRPT)
+	case SRPT_DIST:
+		return newHermodLB(fleet, nGenPerTick, nGSSs, currTickPtr, ctx, LOCAL_SRPT)
+	case PRICE_SRPT:
+		return newIdealLB(fleet, nGenPerTick, currTickPtr, ctx, LOCAL_PRICE_SRPT)
+	case PRICE_SRPT_DIST:
+		return newHermodLB(fleet, nGenPerTick, nGSSs, currTickPtr, ctx, LOCAL_PRICE_SRPT)
+	case EDF:
+		return newEDFLB(fleet, nGenPerTick, currTickPtr, ctx, EDFVariant{ORDER_EDF, EXPIRED_KEEP})
+	case SPARROW:
+		return newSparrowLB(fleet, nGenPerTick, nGSSs, currTickPtr, ctx)
+	case RANDOM:
+		return newBaselineLB(fleet, nGenPerTick, currTickPtr, ctx, PLACE_RANDOM)
+	case ROUND_ROBIN:
+		return newBaselineLB(fleet, nGenPerTick, currTickPtr, ctx, PLACE_ROUND_ROBIN)
+	case JSQ:
+		return newBaselineLB(fleet, nGenPerTick, currTickPtr, ctx, PLACE_JSQ)
+	case POWER_OF_D:
+		return newBaselineLB(fleet, nGenPerTick, currTickPtr, ctx, PLACE_POWER_OF_D)
+	case LLF:
+		return newEDFLB(fleet, nGenPerTick, currTickPtr, ctx, EDFVariant{ORDER_LLF, EXPIRED_KEEP})
+	case EDF_DROP:
+		return newEDFLB(fleet, nGenPerTick, currTickPtr, ctx, EDFVariant{ORDER_EDF, EXPIRED_DROP})
+	case EDF_DEMOTE:
+		return newEDFLB(fleet, nGenPerTick, currTickPtr, ctx, EDFVariant{ORDER_EDF, EXPIRED_DEMOTE})
+	case LLF_DROP:
+		return newEDFLB(fleet, nGenPerTick, currTickPtr, ctx, EDFVariant{ORDER_LLF, EXPIRED_DROP})
+	case LLF_DEMOTE:
+		return newEDFLB(fleet, nGenPerTick, currTickPtr, ctx, EDFVariant{ORDER_LLF, EXPIRED_DEMOTE})
+	case EDF_DIST:
+		return newHermodLB(fleet, nGenPerTick, nGSSs, currTickPtr, ctx, LOCAL_EDF)
+	default:
+		return newMineLB(fleet, nGenPerTick, nGSSs, currTickPtr, ctx)
+	}
 }
 
 func (lbt LBType) string() string {
-	return []string{"mine", "ideal", "hermod", "edf"}[lbt]
+	return []string{"mine", "ideal", "hermod", "edf", "srpt", "srpt-dist", "price-srpt", "price-srpt-dist", "sparrow", "random", "round-robin", "jsq", "power-of-d", "llf", "edf-drop", "edf-demote", "llf-drop", "llf-demote", "edf-dist"}[lbt]
 }
 
 type World struct {
@@ -59,6 +218,10 @@ type World struct {
 }
 
 func newWorld(numMachines int, numCores int, nGenPerTick int, nGSSs int, lbsDoing []LBType) *World {
+	return newWorldWithFleet(uniformFleet(numMachines, numCores), nGenPerTick, nGSSs, lbsDoing)
+}
+
+func newWorldWithFleet(fleet FleetSpec, nGenPerTick int, nGSSs int, lbsDoing []LBType) *World {
 
 	w := &World{
 		currTick:      Tftick(0),
@@ -66,7 +229,7 @@ func newWorld(numMachines int, numCores int, nGenPerTick int, nGSSs int, lbsDoin
 	}
 
 	for _, lbTypeToInclude := range lbsDoing {
-		w.LBs = append(w.LBs, lbTypeToInclude.newLB(numMachines, numCores, nGenPerTick, nGSSs, &w.currTick))
+		w.LBs = append(w.LBs, lbTypeToInclude.newLB(fleet, nGenPerTick, nGSSs, &w.currTick))
 		fmt.Printf("making lb of type %v\n", lbTypeToInclude.string())
 	}
 
@@ -81,12 +244,28 @@ func (w *World) genLoad(nProcs int) []*ProcInternals {
 
 	for _, up := range userProcs {
 
+		procId := Tid(w.currProcNum)
+		w.currProcNum += 1
+
+		// the DAG starts now, and the ids for the rest of it get handed out now so that every LB
+		// uses the same ones; this has to happen before the LBs see the root, since its deadline
+		// depends on when the DAG started
+		if up.dag != nil {
+			up.dag.timeStarted = w.currTick
+			for _, node := range up.dag.nodes[1:] {
+				node.reservedId = Tid(w.currProcNum)
+				w.currProcNum += 1
+			}
+		}
+
 		for _, lb := range w.LBs {
-			provProc := newProvProc(Tid(w.currProcNum), w.currTick, up)
+			provProc := newProvProc(procId, w.currTick, up)
+			lb.getCtx().procArrived(provProc)
+			if !lb.getCtx().admit(provProc) {
+				continue
+			}
 			lb.enqProc(provProc)
 		}
-
-		w.currProcNum += 1
 	}
 	return userProcs
 }
@@ -100,6 +279,9 @@ func (w *World) Tick(numProcs int) {
 
 	for _, lb := range w.LBs {
 		lb.tick()
+		if MARKET_MODE {
+			lb.getCtx().market.tick()
+		}
 	}
 
 	w.currTick += 1
@@ -109,4 +291,8 @@ func (w *World) Run(nTick int) {
 	for i := 0; i < nTick; i++ {
 		w.Tick(w.numProcsToGen)
 	}
+
+	for _, lb := range w.LBs {
+		lb.getCtx().writeStats()
+	}
 }